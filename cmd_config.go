@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"golangHotelProject/internal/config"
+	"os"
+)
+
+// runConfig implements `server config print`: the effective configuration
+// after file, environment and flags, with secrets redacted.
+func runConfig(cfg config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: server [flags] config print")
+		return 2
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "render config:", err)
+		return 1
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"golangHotelProject/internal/app"
+	"golangHotelProject/internal/config"
+	"golangHotelProject/internal/repository/db"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+const migrateUsage = "usage: server [flags] migrate up | down [n] | status | seed"
+
+// runMigrate implements `server migrate ...` and returns the exit code.
+func runMigrate(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	ctx := context.Background()
+
+	conn, err := app.OpenDatabase(ctx, cfg, slog.Default())
+	if err != nil {
+		slog.Error("failed to init database", "error", err.Error())
+		return 1
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing database", "error", err.Error())
+		}
+	}()
+
+	migrator, err := db.NewMigrator(conn, cfg.Storage.Driver)
+	if err != nil {
+		slog.Error("failed to load migrations", "error", err.Error())
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			slog.Error("migrate up failed", "error", err.Error())
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, "down expects a positive number of steps")
+				return 2
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			slog.Error("migrate down failed", "error", err.Error())
+			return 1
+		}
+		fmt.Printf("rolled back %d migration(s)\n", n)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			slog.Error("migrate status failed", "error", err.Error())
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-30s %s\n", s.Version, s.Name, applied)
+		}
+	case "seed":
+		if err := migrator.ApplySeed(ctx); err != nil {
+			slog.Error("seed failed", "error", err.Error())
+			return 1
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
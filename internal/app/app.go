@@ -0,0 +1,154 @@
+// Package app wires storage, usecases and HTTP handlers into one service
+// instance. Nothing in it is global, so several instances can run side by
+// side in one process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"golangHotelProject/internal/config"
+	hn "golangHotelProject/internal/delivery/handlers"
+	"golangHotelProject/internal/logger"
+	"golangHotelProject/internal/metrics"
+	"golangHotelProject/internal/middleware"
+	"golangHotelProject/internal/repository"
+	"golangHotelProject/internal/repository/db"
+	"golangHotelProject/internal/usecase"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type App struct {
+	Config config.Config
+	// DB is nil with the memory driver.
+	DB       *sql.DB
+	Repos    repository.Repositories
+	UoW      repository.UnitOfWork
+	Rooms    *usecase.RoomUsecase
+	Bookings *usecase.BookingUsecase
+	Batch    *usecase.BatchUsecase
+	Handler  *hn.Handler
+
+	Idempotency *middleware.MemoryIdempotencyStore
+	RateLimits  *middleware.MemoryRateLimitStore
+	Metrics     *metrics.Metrics
+	// LogLevels are the levels /admin/log-level reads and changes. They
+	// belong to the process logger, so main sets them; while nil the
+	// endpoint answers 404.
+	LogLevels *logger.Levels
+
+	log      *slog.Logger
+	router   http.Handler
+	migrator *db.Migrator // nil with the memory driver
+
+	workers      sync.WaitGroup
+	workersMu    sync.Mutex
+	workerStates map[string]*workerState
+	// shuttingDown flips when Serve starts draining, so that /readyz takes
+	// the instance out of rotation before the listener closes.
+	shuttingDown atomic.Bool
+}
+
+// New opens the storage cfg points at, migrates it if asked to, and builds
+// everything on top of it. Close releases what New opened.
+func New(ctx context.Context, cfg config.Config, log *slog.Logger) (*App, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+
+	a := &App{Config: cfg, log: log}
+	if err := a.openStorage(ctx); err != nil {
+		return nil, err
+	}
+
+	a.Rooms = usecase.NewRoomUsecase(a.Repos.Rooms, log)
+	a.Rooms.UoW = a.UoW
+	a.Bookings = usecase.NewBookingUsecase(a.Repos.Bookings, a.UoW, log)
+	a.Batch = usecase.NewBatchUsecase(a.UoW, log)
+
+	handler, err := hn.NewHandler(a.Rooms, a.Bookings, a.Batch)
+	if err != nil {
+		_ = a.Close()
+		return nil, fmt.Errorf("handlers init: %w", err)
+	}
+	a.Handler = handler
+
+	a.Idempotency = middleware.NewMemoryIdempotencyStore()
+	a.RateLimits = middleware.NewMemoryRateLimitStore()
+
+	a.Metrics = metrics.New()
+	if a.DB != nil {
+		a.Metrics.RegisterDB(a.DB, cfg.Storage.Driver)
+	}
+	a.Metrics.RegisterRoomStats(a.Rooms, cfg.Health.CheckTimeout, log)
+
+	router, err := a.routes()
+	if err != nil {
+		_ = a.Close()
+		return nil, err
+	}
+	a.router = router
+	return a, nil
+}
+
+// ServeHTTP makes the App usable directly as the server's handler and with
+// httptest.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.router.ServeHTTP(w, r)
+}
+
+// StartWorkers runs the background jobs until ctx is done. Serve calls it
+// and waits for the jobs to return before it does.
+func (a *App) StartWorkers(ctx context.Context) {
+	a.goWorker(ctx, "idempotency_janitor", func() { a.Idempotency.RunJanitor(ctx, time.Minute) })
+	a.goWorker(ctx, "rate_limit_janitor", func() { a.RateLimits.RunJanitor(ctx, time.Minute) })
+}
+
+type workerState struct {
+	running bool
+	err     string
+}
+
+// goWorker runs fn in the background and records its state for /readyz. A
+// worker that returns or panics before ctx is done counts as failed.
+func (a *App) goWorker(ctx context.Context, name string, fn func()) {
+	a.setWorkerState(name, workerState{running: true})
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		defer func() {
+			state := workerState{}
+			if p := recover(); p != nil {
+				state.err = fmt.Sprintf("panic: %v", p)
+				a.log.Error("background worker panicked", "worker", name, "panic", p)
+			} else if ctx.Err() == nil {
+				state.err = "exited unexpectedly"
+				a.log.Error("background worker exited unexpectedly", "worker", name)
+			}
+			a.setWorkerState(name, state)
+		}()
+		fn()
+	}()
+}
+
+func (a *App) setWorkerState(name string, state workerState) {
+	a.workersMu.Lock()
+	defer a.workersMu.Unlock()
+	if a.workerStates == nil {
+		a.workerStates = make(map[string]*workerState)
+	}
+	a.workerStates[name] = &state
+}
+
+// Close releases the storage. Call it after Serve has returned so that no
+// request still uses the database.
+func (a *App) Close() error {
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.Close()
+}
@@ -0,0 +1,342 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/config"
+	"golangHotelProject/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverMemory
+
+	a, err := New(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	return a
+}
+
+func TestApp_InstancesAreIsolated(t *testing.T) {
+	first := newTestApp(t)
+	second := newTestApp(t)
+
+	body := `{"number":101,"room_count":1,"floor":1,"sleeping_places":2,"room_type":"Standard"}`
+	for _, a := range []*App{first, second} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body)))
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, "/ReadRoomByID?id=1", rec.Header().Get("Location"))
+	}
+
+	rec := httptest.NewRecorder()
+	first.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	second.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ReadRoomByID?id=2", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNew_UnknownDriver(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Driver = "oracle"
+
+	_, err := New(context.Background(), cfg, nil)
+
+	assert.ErrorContains(t, err, "unknown storage driver")
+}
+
+func TestServe_DrainsInFlightRequestsOnShutdown(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.HTTP.ShutdownTimeout = 5 * time.Second
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	a.router = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- a.Serve(ctx, ln) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	got := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			got <- result{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		got <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-got
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.NoError(t, <-served)
+
+	_, err = net.Dial("tcp", ln.Addr().String())
+	assert.Error(t, err, "listener should be closed after shutdown")
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	a := newTestApp(t)
+
+	body := `{"number":101,"room_count":1,"floor":1,"sleeping_places":2,"room_type":"Standard"}`
+	for range 2 {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body)))
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	out := rec.Body.String()
+	assert.Contains(t, out, `hotel_http_requests_total{code="201",handler="room.create",method="POST"} 1`)
+	assert.Contains(t, out, `hotel_http_requests_total{code="409",handler="room.create",method="POST"} 1`)
+	assert.Contains(t, out, `hotel_usecase_errors_total{handler="room.create",kind="conflict"} 1`)
+	assert.Contains(t, out, `hotel_http_request_duration_seconds_bucket{handler="room.create",method="POST"`)
+	assert.Contains(t, out, "hotel_rooms 1")
+	assert.Contains(t, out, "hotel_rooms_occupied 0")
+}
+
+func TestRequestID_ReachesEveryLogLine(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverMemory
+	a, err := New(context.Background(), cfg, slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	require.NoError(t, err)
+	buf.Reset()
+
+	body := `{"number":101,"room_count":1,"floor":1,"sleeping_places":2,"room_type":"Standard"}`
+	req := httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body))
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "req-42", rec.Header().Get("X-Request-ID"))
+
+	var handlerLines, usecaseLines int
+	for _, raw := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(raw, &line))
+		assert.Equal(t, "req-42", line["request_id"], string(raw))
+		if line["handler"] == "room.create" {
+			handlerLines++
+		}
+		if line["component"] == "RoomUsecase" {
+			usecaseLines++
+		}
+	}
+	assert.Positive(t, handlerLines)
+	assert.Positive(t, usecaseLines)
+}
+
+func TestRequestID_ReplayKeepsTheRetrysID(t *testing.T) {
+	a := newTestApp(t)
+
+	body := `{"number":101,"room_count":1,"floor":1,"sleeping_places":2,"room_type":"Standard"}`
+	for _, id := range []string{"first", "retry"} {
+		req := httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "k1")
+		req.Header.Set("X-Request-ID", id)
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, id, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestRateLimit_CreateBookingIsLimitedPerClient(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverMemory
+	cfg.RateLimit.Booking = config.RateLimitGroup{Requests: 1, Per: time.Hour, Burst: 2}
+	a, err := New(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+
+	book := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/CreateBooking", strings.NewReader(`{}`))
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for range 2 {
+		assert.NotEqual(t, http.StatusTooManyRequests, book("203.0.113.7:1000").Code)
+	}
+	rec := book("203.0.113.7:1001")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+	assert.NotEqual(t, http.StatusTooManyRequests, book("198.51.100.1:1000").Code, "other clients are not affected")
+
+	search := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/GetFilteredRooms", nil)
+	req.RemoteAddr = "203.0.113.7:1002"
+	a.ServeHTTP(search, req)
+	assert.NotEqual(t, http.StatusTooManyRequests, search.Code, "route groups have separate budgets")
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverMemory
+	cfg.Log.AdminToken = "s3cret"
+	a, err := New(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+
+	call := func(method, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "", "").Code, "no levels to control")
+
+	a.LogLevels = logger.NewLevels(slog.LevelInfo)
+	a.LogLevels.SetComponent("Migrator", slog.LevelWarn)
+
+	rec := call(http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO","components":{"Migrator":"WARN"}}`, rec.Body.String())
+
+	change := `{"level":"warn","components":{"RoomUsecase":"DEBUG","Migrator":""}}`
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodPatch, change, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodPatch, change, "guess").Code)
+
+	rec = call(http.MethodPatch, `{"level":"DEBUG","components":{"RoomUsecase":"LOUD"}}`, "s3cret")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, slog.LevelInfo, a.LogLevels.Base(), "an invalid request changes nothing")
+
+	rec = call(http.MethodPatch, change, "s3cret")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"level":"WARN","components":{"RoomUsecase":"DEBUG"}}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, a.LogLevels.Level("RoomUsecase"))
+	assert.Equal(t, slog.LevelWarn, a.LogLevels.Level("Migrator"), "Migrator follows the base level again")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, call(http.MethodPost, change, "s3cret").Code)
+
+	huge := `{"components":{"` + strings.Repeat("x", 1<<17) + `":"DEBUG"}}`
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPatch, huge, "s3cret").Code)
+
+	a.Config.Log.AdminToken = ""
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "", "").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodPatch, change, "").Code, "changes are off without an admin token")
+}
+
+func TestValidationErrorsAreListedPerField(t *testing.T) {
+	a := newTestApp(t)
+
+	rec := httptest.NewRecorder()
+	body := `{"number":101,"room_count":0,"floor":0,"sleeping_places":2,"room_type":"Palace"}`
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"error": "validation failed",
+		"fields": [
+			{"field": "room_count", "code": "min", "message": "must be at least 1"},
+			{"field": "floor", "code": "min", "message": "must be at least 1"},
+			{"field": "room_type", "code": "one_of", "message": "must be one of: Standard, Deluxe, Suite"}
+		]
+	}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ReadRoomByID?id=7", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"code":"room_not_found","error":"room not found"}`, rec.Body.String(), "other usecase errors use the same envelope")
+}
+
+func TestErrorMessagesFollowAcceptLanguage(t *testing.T) {
+	a := newTestApp(t)
+
+	call := func(method, target, body, lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := `{"number":101,"room_count":1,"floor":0,"sleeping_places":2,"room_type":"Palace"}`
+	rec := call(http.MethodPost, "/Create", body, "ru-RU,ru;q=0.9,en;q=0.8")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "ru", rec.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"error": "ошибка валидации",
+		"fields": [
+			{"field": "floor", "code": "min", "message": "должно быть не меньше 1"},
+			{"field": "room_type", "code": "one_of", "message": "должно быть одним из: Standard, Deluxe, Suite"}
+		]
+	}`, rec.Body.String())
+
+	rec = call(http.MethodGet, "/ReadRoomByID?id=7", "", "ru")
+	assert.JSONEq(t, `{"code":"room_not_found","error":"номер не найден"}`, rec.Body.String())
+
+	rec = call(http.MethodGet, "/ReadRoomByID?id=x", "", "de, en;q=0.5")
+	assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"code":"invalid_id","error":"id must be a positive integer"}`, rec.Body.String())
+
+	rec = call(http.MethodPost, "/ReadRoomByID?id=7", "", "ru")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.JSONEq(t, `{"code":"method_not_allowed","error":"метод не поддерживается"}`, rec.Body.String())
+
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverMemory
+	cfg.I18n.DefaultLanguage = "ru"
+	a, err := New(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+
+	rec = call(http.MethodGet, "/ReadRoomByID?id=7", "", "")
+	assert.Equal(t, "ru", rec.Header().Get("Content-Language"), "without Accept-Language the default language answers")
+	assert.JSONEq(t, `{"code":"room_not_found","error":"номер не найден"}`, rec.Body.String())
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// PoolStats is sql.DBStats in a JSON friendly form.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+func newPoolStats(s sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMS:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+type dbStatsResponse struct {
+	Driver string     `json:"driver"`
+	Pool   *PoolStats `json:"pool,omitempty"`
+}
+
+// dbStats reports the connection pool counters to holders of the admin
+// token. The memory driver has no pool, so only the driver is reported.
+func (a *App) dbStats(w http.ResponseWriter, r *http.Request) {
+	if !a.requireAdmin(w, r) {
+		return
+	}
+	resp := dbStatsResponse{Driver: a.Config.Storage.Driver}
+	if a.DB != nil {
+		stats := newPoolStats(a.DB.Stats())
+		resp.Pool = &stats
+	}
+	writeProbe(w, http.StatusOK, resp)
+}
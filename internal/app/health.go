@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusDisabled    = "disabled"
+)
+
+type CheckResult struct {
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+}
+
+type ReadinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// livez reports that the process is up and serving. It checks nothing
+// else: restarting the instance would not fix a database outage.
+func (a *App) livez(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, map[string]string{"status": statusOK})
+}
+
+// readyz reports whether the instance should receive traffic. It answers
+// 503 while any dependency check fails and once shutdown has begun.
+func (a *App) readyz(w http.ResponseWriter, r *http.Request) {
+	report := a.Readiness(r.Context())
+	code := http.StatusOK
+	if report.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeProbe(w, code, report)
+}
+
+// Readiness runs every dependency check within health.check_timeout.
+func (a *App) Readiness(ctx context.Context) ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, a.Config.Health.CheckTimeout)
+	defer cancel()
+
+	report := ReadinessReport{
+		Status: statusOK,
+		Checks: map[string]CheckResult{
+			"database":   a.checkDatabase(ctx),
+			"migrations": a.checkMigrations(ctx),
+			"workers":    a.checkWorkers(),
+		},
+	}
+	if a.shuttingDown.Load() {
+		report.Checks["shutdown"] = CheckResult{Status: statusUnavailable, Detail: "draining connections"}
+	}
+	for _, c := range report.Checks {
+		if c.Status == statusUnavailable {
+			report.Status = statusUnavailable
+		}
+	}
+	return report
+}
+
+func (a *App) checkDatabase(ctx context.Context) CheckResult {
+	if a.DB == nil {
+		return CheckResult{Status: statusOK, Detail: a.Config.Storage.Driver}
+	}
+	start := time.Now()
+	err := a.DB.PingContext(ctx)
+	res := CheckResult{Status: statusOK, Detail: a.Config.Storage.Driver, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status, res.Error = statusUnavailable, err.Error()
+	}
+	return res
+}
+
+func (a *App) checkMigrations(ctx context.Context) CheckResult {
+	if a.migrator == nil {
+		return CheckResult{Status: statusDisabled, Detail: "no schema for the " + a.Config.Storage.Driver + " driver"}
+	}
+	statuses, err := a.migrator.Status(ctx)
+	if err != nil {
+		return CheckResult{Status: statusUnavailable, Error: err.Error()}
+	}
+	applied := 0
+	for _, s := range statuses {
+		if s.Applied {
+			applied++
+		}
+	}
+	res := CheckResult{Status: statusOK, Detail: fmt.Sprintf("%d/%d applied", applied, len(statuses))}
+	if applied < len(statuses) {
+		res.Status = statusUnavailable
+		res.Error = fmt.Sprintf("%d pending migration(s)", len(statuses)-applied)
+	}
+	return res
+}
+
+func (a *App) checkWorkers() CheckResult {
+	a.workersMu.Lock()
+	defer a.workersMu.Unlock()
+
+	if len(a.workerStates) == 0 {
+		return CheckResult{Status: statusOK, Detail: "none started"}
+	}
+
+	names := make([]string, 0, len(a.workerStates))
+	for name := range a.workerStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := CheckResult{Status: statusOK}
+	var details, failures []string
+	for _, name := range names {
+		state := a.workerStates[name]
+		switch {
+		case state.err != "":
+			failures = append(failures, name+": "+state.err)
+		case state.running:
+			details = append(details, name+": running")
+		default:
+			details = append(details, name+": stopped")
+		}
+	}
+	res.Detail = strings.Join(details, ", ")
+	if len(failures) > 0 {
+		res.Status = statusUnavailable
+		res.Error = strings.Join(failures, ", ")
+	}
+	return res
+}
+
+func writeProbe(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, a *App, path string) (int, ReadinessReport) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report ReadinessReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report), rec.Body.String())
+	return rec.Code, report
+}
+
+func newSQLiteApp(t *testing.T, migrate bool) *App {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Storage.Driver = DriverSQLite
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "hotel.db")
+	cfg.Storage.MigrateOnStart = migrate
+
+	a, err := New(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	return a
+}
+
+func TestProbes_Memory(t *testing.T) {
+	a := newTestApp(t)
+
+	code, report := probe(t, a, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", report.Status)
+
+	code, report = probe(t, a, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", report.Checks["database"].Status)
+	assert.Equal(t, "disabled", report.Checks["migrations"].Status)
+}
+
+func TestReadyz_PendingMigrations(t *testing.T) {
+	a := newSQLiteApp(t, false)
+
+	code, report := probe(t, a, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "ok", report.Checks["database"].Status)
+	assert.Equal(t, "unavailable", report.Checks["migrations"].Status)
+	assert.Contains(t, report.Checks["migrations"].Error, "pending")
+
+	_, err := a.migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	code, report = probe(t, a, "/readyz")
+	assert.Equal(t, http.StatusOK, code, report)
+	assert.Equal(t, "2/2 applied", report.Checks["migrations"].Detail)
+}
+
+func TestReadyz_DatabaseDown(t *testing.T) {
+	a := newSQLiteApp(t, true)
+	require.NoError(t, a.DB.Close())
+
+	code, report := probe(t, a, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", report.Checks["database"].Status)
+	assert.NotEmpty(t, report.Checks["database"].Error)
+
+	code, _ = probe(t, a, "/livez")
+	assert.Equal(t, http.StatusOK, code, "liveness does not depend on the database")
+}
+
+func TestReadyz_FailedWorker(t *testing.T) {
+	a := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a.StartWorkers(ctx)
+	a.goWorker(ctx, "broken", func() { panic("boom") })
+
+	var report ReadinessReport
+	require.Eventually(t, func() bool {
+		var code int
+		code, report = probe(t, a, "/readyz")
+		return code == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, report.Checks["workers"].Error, "broken: panic: boom")
+	assert.Contains(t, report.Checks["workers"].Detail, "idempotency_janitor: running")
+}
+
+func TestReadyz_NotReadyWhileShuttingDown(t *testing.T) {
+	a := newTestApp(t)
+	a.shuttingDown.Store(true)
+
+	code, report := probe(t, a, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", report.Checks["shutdown"].Status)
+}
+
+func TestDBStats(t *testing.T) {
+	get := func(a *App, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/debug/dbstats", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	a := newSQLiteApp(t, true)
+	assert.Equal(t, http.StatusNotFound, get(a, "").Code, "off without an admin token")
+
+	a.Config.Log.AdminToken = "s3cret"
+	assert.Equal(t, http.StatusUnauthorized, get(a, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(a, "guess").Code)
+
+	rec := get(a, "s3cret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dbStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, DriverSQLite, resp.Driver)
+	require.NotNil(t, resp.Pool)
+	assert.Positive(t, resp.Pool.OpenConnections)
+
+	memory := newTestApp(t)
+	memory.Config.Log.AdminToken = "s3cret"
+	assert.JSONEq(t, `{"driver":"memory"}`, get(memory, "s3cret").Body.String())
+}
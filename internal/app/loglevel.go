@@ -0,0 +1,124 @@
+package app
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"golangHotelProject/internal/logger"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// LogLevels is the body of /admin/log-level. In a PATCH both fields are
+// optional; an empty component level removes the override.
+type LogLevels struct {
+	Level      string            `json:"level,omitempty"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// logLevel reports the log levels on GET and changes them on PATCH, which
+// needs the admin token.
+func (a *App) logLevel(w http.ResponseWriter, r *http.Request) {
+	if a.LogLevels == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		if !a.requireAdmin(w, r) {
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<16)
+		if err := a.changeLogLevels(r); err != nil {
+			writeProbe(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PATCH")
+		writeProbe(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	writeProbe(w, http.StatusOK, a.currentLogLevels())
+}
+
+// requireAdmin reports whether r carries the admin token and answers the
+// request otherwise: with 404 while no token is configured, since the admin
+// operations are off then, and with 401 for a missing or wrong token.
+func (a *App) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	token := a.Config.Log.AdminToken
+	if token == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeProbe(w, http.StatusUnauthorized, map[string]string{"error": "admin token required"})
+		return false
+	}
+	return true
+}
+
+// changeLogLevels validates the whole request before applying any of it.
+func (a *App) changeLogLevels(r *http.Request) error {
+	var req LogLevels
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return err
+	}
+
+	base, setBase := a.LogLevels.Base(), req.Level != ""
+	if setBase {
+		level, err := logger.ParseLevel(req.Level)
+		if err != nil {
+			return err
+		}
+		base = level
+	}
+	components := make(map[string]*slog.Level, len(req.Components))
+	for component, s := range req.Components {
+		if s == "" {
+			components[component] = nil
+			continue
+		}
+		level, err := logger.ParseLevel(s)
+		if err != nil {
+			return err
+		}
+		components[component] = &level
+	}
+
+	if setBase {
+		a.LogLevels.SetBase(base)
+	}
+	for component, level := range components {
+		if level == nil {
+			a.LogLevels.ResetComponent(component)
+		} else {
+			a.LogLevels.SetComponent(component, *level)
+		}
+	}
+
+	logger.FromContextOr(r.Context(), a.log).Info("log levels changed",
+		"level", a.LogLevels.Base().String(),
+		"components", slices.Sorted(maps.Keys(components)),
+	)
+	return nil
+}
+
+func (a *App) currentLogLevels() LogLevels {
+	resp := LogLevels{Level: a.LogLevels.Base().String()}
+	if overrides := a.LogLevels.Components(); len(overrides) > 0 {
+		resp.Components = make(map[string]string, len(overrides))
+		for component, level := range overrides {
+			resp.Components[component] = level.String()
+		}
+	}
+	return resp
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"golangHotelProject/internal/config"
+	"golangHotelProject/internal/middleware"
+	"golangHotelProject/internal/tracing"
+	"net/http"
+
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+func (a *App) routes() (http.Handler, error) {
+	h := a.Handler
+	proxies, err := middleware.ParseTrustedProxies(a.Config.RateLimit.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	clients := middleware.RateLimitKey(a.Config.RateLimit.Key, proxies)
+	idempotent := middleware.Idempotency(a.Idempotency, a.Config.Idempotency.TTL, clients)
+	limits := a.rateLimits(clients)
+	mux := http.NewServeMux()
+
+	// The names are the handler labels of the metrics and traces and match
+	// the ones the handlers log with. Refused requests are measured and
+	// traced as the 429 they answer with. Recover runs inside so that a
+	// panicking handler is measured and traced as the 500 it answers with.
+	route := func(pattern, name, group string, handler http.Handler) {
+		perRoute := middleware.Chain(
+			func(h http.Handler) http.Handler { return tracing.Middleware(name, h) },
+			func(h http.Handler) http.Handler { return a.Metrics.Instrument(name, h) },
+			limits[group],
+			middleware.Recover,
+		)
+		mux.Handle(pattern, perRoute(handler))
+	}
+
+	route("/Create", "room.create", "default", idempotent(http.HandlerFunc(h.Create)))
+	route("/ReadRoomByID", "room.readByID", "search", http.HandlerFunc(h.ReadRoomByID))
+	route("/RemoveRoom", "room.remove", "default", http.HandlerFunc(h.RemoveRoom))
+	route("/Patch", "room.patch", "default", http.HandlerFunc(h.Patch))
+	route("/GetFilteredRooms", "room.filter", "search", http.HandlerFunc(h.GetFilteredRooms))
+
+	route("/CreateBooking", "booking.create", "booking", idempotent(http.HandlerFunc(h.CreateBooking)))
+	route("/ReadBookingByID", "booking.readByID", "search", http.HandlerFunc(h.ReadBookingByID))
+	route("/PatchBookingByID", "booking.patch", "default", http.HandlerFunc(h.PatchBookingByID))
+	route("/RemoveBooking", "booking.remove", "default", http.HandlerFunc(h.RemoveBooking))
+	route("/GetFilteredBookings", "booking.getFiltered", "search", http.HandlerFunc(h.GetFilteredBookings))
+	route("/CheckIn", "booking.checkIn", "default", http.HandlerFunc(h.CheckIn))
+	route("/CheckOut", "booking.checkOut", "default", http.HandlerFunc(h.CheckOut))
+
+	route("/Batch", "batch.execute", "default", idempotent(http.HandlerFunc(h.Batch)))
+
+	mux.Handle("/swagger/", httpSwagger.WrapHandler)
+
+	mux.HandleFunc("/livez", a.livez)
+	mux.HandleFunc("/readyz", a.readyz)
+	// /health predates the probes and is kept for existing health checks.
+	mux.HandleFunc("/health", a.livez)
+	mux.HandleFunc("/debug/dbstats", a.dbStats)
+	mux.HandleFunc("/admin/log-level", a.logLevel)
+	mux.Handle("/metrics", a.Metrics.Handler())
+
+	// Every request, routed or not, goes through these, outermost first.
+	global := middleware.Chain(
+		middleware.RequestID(a.log),
+		middleware.AccessLog,
+		// Language comes before Recover so that a panic is answered in the
+		// language of the request too.
+		middleware.Language(a.Config.I18n.DefaultLanguage),
+		middleware.Recover,
+		middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   a.Config.CORS.AllowedOrigins,
+			AllowedMethods:   a.Config.CORS.AllowedMethods,
+			AllowedHeaders:   a.Config.CORS.AllowedHeaders,
+			ExposedHeaders:   a.Config.CORS.ExposedHeaders,
+			AllowCredentials: a.Config.CORS.AllowCredentials,
+			MaxAge:           a.Config.CORS.MaxAge,
+		}),
+	)
+	return global(mux), nil
+}
+
+// rateLimits builds the rate limiter of every route group, telling clients
+// apart by clients. With rate limiting disabled they pass every request
+// through.
+func (a *App) rateLimits(clients func(*http.Request) []string) map[string]middleware.Middleware {
+	cfg := a.Config.RateLimit
+	groups := map[string]config.RateLimitGroup{
+		"booking": cfg.Booking,
+		"search":  cfg.Search,
+		"default": cfg.Default,
+	}
+
+	limits := make(map[string]middleware.Middleware, len(groups))
+	if !cfg.Enabled {
+		for name := range groups {
+			limits[name] = middleware.Chain()
+		}
+		return limits
+	}
+
+	for name, g := range groups {
+		rate := middleware.Rate{Requests: g.Requests, Per: g.Per, Burst: g.Burst}
+		limits[name] = middleware.RateLimit(a.RateLimits, name, rate, clients)
+	}
+	return limits
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Run serves HTTP on the configured address until ctx is cancelled, then
+// shuts down gracefully. See Serve.
+func (a *App) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.Config.HTTP.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", a.Config.HTTP.Addr, err)
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve runs the background workers and serves HTTP on ln until ctx is
+// cancelled or the server fails. On the way out it stops accepting
+// connections, waits up to http.shutdown_timeout for in-flight requests, then
+// stops the workers. The storage stays open; Close it after Serve returns.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	cfg := a.Config.HTTP
+	srv := &http.Server{
+		Handler:           a,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	a.StartWorkers(workersCtx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		a.log.Info("server listening", "addr", ln.Addr().String())
+		serveErr <- srv.Serve(ln)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		a.log.Error("server stopped unexpectedly", "error", err.Error())
+	case <-ctx.Done():
+		a.shuttingDown.Store(true)
+		a.log.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err = srv.Shutdown(shutdownCtx); err != nil {
+			a.log.Error("graceful shutdown failed, closing connections", "error", err.Error())
+			_ = srv.Close()
+		}
+		if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+			err = errors.Join(err, serveErr)
+		}
+	}
+
+	stopWorkers()
+	a.workers.Wait()
+	a.log.Info("server stopped")
+	return err
+}
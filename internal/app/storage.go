@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"golangHotelProject/internal/config"
+	"golangHotelProject/internal/repository"
+	"golangHotelProject/internal/repository/db"
+	"log/slog"
+)
+
+const (
+	DriverPostgres = db.DriverPostgres
+	DriverSQLite   = db.DriverSQLite
+	DriverMemory   = "memory"
+)
+
+// OpenDatabase connects to the SQL database behind cfg.Storage.Driver. For
+// Postgres it retries with backoff until postgres.connect_timeout, so the
+// service can start before the database does.
+func OpenDatabase(ctx context.Context, cfg config.Config, log *slog.Logger) (*sql.DB, error) {
+	switch cfg.Storage.Driver {
+	case DriverPostgres:
+		pg := cfg.Postgres
+		conn, err := db.OpenPostgres(pg.DSN(), db.PoolOptions{
+			MaxOpenConns:    pg.MaxOpenConns,
+			MaxIdleConns:    pg.MaxIdleConns,
+			ConnMaxLifetime: pg.ConnMaxLifetime,
+			ConnMaxIdleTime: pg.ConnMaxIdleTime,
+		})
+		if err != nil {
+			return nil, err
+		}
+		backoff := db.Backoff{Initial: pg.ConnectBackoff, Max: pg.ConnectMaxBackoff, Timeout: pg.ConnectTimeout}
+		if err := db.WaitReady(ctx, conn, backoff, log); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	case DriverSQLite:
+		return db.OpenSQLite(cfg.Storage.SQLitePath)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q, want %s, %s or %s", cfg.Storage.Driver, DriverPostgres, DriverSQLite, DriverMemory)
+}
+
+func (a *App) openStorage(ctx context.Context) error {
+	cfg := a.Config
+	if cfg.Storage.Driver == DriverMemory {
+		a.log.Warn("using in-memory storage, data is lost on restart")
+		store := repository.NewMemoryStore()
+		a.Repos = repository.Repositories{Rooms: store.Rooms(), Bookings: store.Bookings()}
+		a.UoW = &repository.MemoryUnitOfWork{Store: store}
+		return nil
+	}
+
+	conn, err := OpenDatabase(ctx, cfg, a.log)
+	if err != nil {
+		return err
+	}
+	a.migrator, err = db.NewMigrator(conn, cfg.Storage.Driver)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if err := a.migrate(ctx); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("migrate: %w", err)
+	}
+	a.DB = conn
+
+	traced := db.Traced(conn, cfg.Storage.Driver)
+	if cfg.Storage.Driver == DriverSQLite {
+		a.Repos = repository.Repositories{
+			Rooms:    &repository.SQLiteRoomRepository{DB: traced},
+			Bookings: &repository.SQLiteBookingRepository{DB: traced},
+		}
+		a.UoW = &repository.SQLiteUnitOfWork{DB: conn}
+		return nil
+	}
+	a.Repos = repository.Repositories{
+		Rooms:    &repository.PgRoomRepository{DB: traced},
+		Bookings: &repository.PgBookingRepository{DB: traced},
+	}
+	a.UoW = &repository.PgUnitOfWork{DB: conn}
+	return nil
+}
+
+// migrate brings the schema up to date before the server starts serving and
+// loads the demo data when storage.seed is set.
+func (a *App) migrate(ctx context.Context) error {
+	if !a.Config.Storage.MigrateOnStart {
+		a.log.Info("migrate on start disabled")
+		return nil
+	}
+
+	n, err := a.migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	a.log.Info("schema is up to date", "applied", n)
+
+	if a.Config.Storage.Seed {
+		return a.migrator.ApplySeed(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing_SpansFromHandlerToSQL(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	a := newSQLiteApp(t, true)
+
+	body := `{"number":101,"room_count":1,"floor":1,"sleeping_places":2,"room_type":"Standard"}`
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/Create", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans.Ended() {
+		byName[s.Name()] = s
+	}
+	server, usecase, insert := byName["POST /Create"], byName["RoomUsecase.AddRoom"], byName["INSERT"]
+	require.NotNil(t, server)
+	require.NotNil(t, usecase)
+	require.NotNil(t, insert)
+
+	assert.Equal(t, server.SpanContext().SpanID(), usecase.Parent().SpanID())
+	assert.Equal(t, usecase.SpanContext().SpanID(), insert.Parent().SpanID())
+	assert.Equal(t, server.SpanContext().TraceID(), insert.SpanContext().TraceID())
+
+	var statement string
+	for _, kv := range insert.Attributes() {
+		if kv.Key == "db.query.text" {
+			statement = kv.Value.AsString()
+		}
+	}
+	assert.Contains(t, statement, "INSERT INTO rooms")
+}
@@ -0,0 +1,781 @@
+// Package config loads the service configuration. Every setting has a
+// default, which a YAML file, then environment variables, then command-line
+// flags can override.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/logger"
+	"io"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+type Config struct {
+	HTTP        HTTP        `yaml:"http"`
+	CORS        CORS        `yaml:"cors"`
+	Log         Log         `yaml:"log"`
+	Storage     Storage     `yaml:"storage"`
+	Postgres    Postgres    `yaml:"postgres"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Health      Health      `yaml:"health"`
+	Tracing     Tracing     `yaml:"tracing"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	I18n        I18n        `yaml:"i18n"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr"`
+
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// after SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type CORS struct {
+	// AllowedOrigins holds exact origins, patterns with one wildcard such as
+	// https://*.example.com, or * for any origin.
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+type Log struct {
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+	// Components overrides Level for the loggers of single components, e.g.
+	// RoomUsecase: DEBUG.
+	Components map[string]string `yaml:"components"`
+	// File, when set, receives the log lines as well as stdout. It is
+	// rotated at FileMaxSizeMB, keeping FileMaxBackups old files.
+	File           string `yaml:"file"`
+	FileMaxSizeMB  int    `yaml:"file_max_size_mb"`
+	FileMaxBackups int    `yaml:"file_max_backups"`
+	// AdminToken has to be sent as a bearer token to change the levels
+	// through /admin/log-level and to read /debug/dbstats. Without it both
+	// answer 404.
+	AdminToken string `yaml:"admin_token"`
+	Redact     Redact `yaml:"redact"`
+}
+
+// Redact hides personal data in the logs: the values of Keys and whatever
+// matches Patterns (email, phone, document or a regular expression).
+type Redact struct {
+	Enabled  bool     `yaml:"enabled"`
+	Keys     []string `yaml:"keys"`
+	Patterns []string `yaml:"patterns"`
+	// Mode is mask, which writes [REDACTED], or hash, which writes a salted
+	// hash so that lines about the same person can be matched up. hash needs
+	// a secret HashSalt.
+	Mode     string `yaml:"mode"`
+	HashSalt string `yaml:"hash_salt"`
+}
+
+type Storage struct {
+	// Driver is postgres, sqlite or memory.
+	Driver         string `yaml:"driver"`
+	SQLitePath     string `yaml:"sqlite_path"`
+	MigrateOnStart bool   `yaml:"migrate_on_start"`
+	Seed           bool   `yaml:"seed"`
+}
+
+type Postgres struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// PasswordFile, when set, is read into Password, e.g. a Docker secret.
+	PasswordFile string `yaml:"password_file"`
+	Name         string `yaml:"name"`
+	SSLMode      string `yaml:"sslmode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// ConnectTimeout is how long startup keeps retrying an unreachable
+	// server, starting ConnectBackoff apart and doubling up to
+	// ConnectMaxBackoff.
+	ConnectTimeout    time.Duration `yaml:"connect_timeout"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff"`
+}
+
+type Idempotency struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type Health struct {
+	// CheckTimeout bounds all dependency checks of one /readyz request and
+	// the database reads behind the business gauges of one /metrics scrape.
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp.
+	Exporter     string `yaml:"exporter"`
+	ServiceName  string `yaml:"service_name"`
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	OTLPInsecure bool   `yaml:"otlp_insecure"`
+	// SampleRatio is the share of new traces that are recorded; requests
+	// arriving with a sampled traceparent are always recorded.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled"`
+	// Key is what clients are told apart by: ip, api_key (X-API-Key) or user
+	// (the Authorization credentials). The service does not check those
+	// credentials, so the last two limit the client IP as well and are meant
+	// for running behind an authenticating proxy.
+	Key string `yaml:"key"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// whose X-Forwarded-For is believed.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// Booking limits /CreateBooking, Search the read and search endpoints
+	// and Default the other API routes.
+	Booking RateLimitGroup `yaml:"booking"`
+	Search  RateLimitGroup `yaml:"search"`
+	Default RateLimitGroup `yaml:"default"`
+}
+
+// RateLimitGroup allows Requests per Per on average and bursts of up to
+// Burst requests; a zero Burst means Requests.
+type RateLimitGroup struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+type I18n struct {
+	// DefaultLanguage is the language of the API messages for requests whose
+	// Accept-Language names no supported language: en or ru.
+	DefaultLanguage string `yaml:"default_language"`
+}
+
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"http://localhost:3000"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "If-Match", "Idempotency-Key", "X-Request-ID", "X-API-Key", "traceparent", "tracestate"},
+			ExposedHeaders: []string{"ETag", "Location", "Idempotent-Replayed", "X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
+			MaxAge:         10 * time.Minute,
+		},
+		Log: Log{
+			Level:          "INFO",
+			Format:         "json",
+			FileMaxSizeMB:  100,
+			FileMaxBackups: 3,
+			Redact: Redact{
+				Enabled:  true,
+				Keys:     slices.Clone(logger.DefaultRedactKeys),
+				Patterns: []string{"email", "phone", "document"},
+				Mode:     "mask",
+			},
+		},
+		Storage: Storage{
+			Driver:         "postgres",
+			SQLitePath:     "hotel.db",
+			MigrateOnStart: true,
+		},
+		Postgres: Postgres{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Name:    "hotel",
+			SSLMode: "disable",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			ConnectTimeout:    30 * time.Second,
+			ConnectBackoff:    500 * time.Millisecond,
+			ConnectMaxBackoff: 5 * time.Second,
+		},
+		Idempotency: Idempotency{TTL: 24 * time.Hour},
+		Health:      Health{CheckTimeout: 2 * time.Second},
+		Tracing: Tracing{
+			Exporter:     "none",
+			ServiceName:  "hotel-booking",
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+			SampleRatio:  1,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Key:     "ip",
+			Booking: RateLimitGroup{Requests: 30, Per: time.Minute, Burst: 10},
+			Search:  RateLimitGroup{Requests: 300, Per: time.Minute, Burst: 60},
+			Default: RateLimitGroup{Requests: 120, Per: time.Minute, Burst: 40},
+		},
+		I18n: I18n{DefaultLanguage: i18n.English},
+	}
+}
+
+// Load builds the effective configuration from args (usually os.Args[1:])
+// and the environment read through getenv. The config file comes from
+// -config or CONFIG_FILE. Load returns the arguments left after the flags,
+// i.e. the subcommand.
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", getenv("CONFIG_FILE"), "path to a YAML config file")
+	flags := bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return Config{}, nil, err
+	}
+	flags.apply(fs, &cfg)
+	if err := cfg.resolveSecrets(); err != nil {
+		return Config{}, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	// An empty file decodes to io.EOF and leaves the defaults alone.
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides c with the environment variables the service has always
+// read, plus a few newer ones.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	str := func(name string, dst *string) {
+		if v := getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	var errs []error
+	duration := func(name string, dst *time.Duration) {
+		if v := getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, v, err))
+				return
+			}
+			*dst = d
+		}
+	}
+	list := func(name string, dst *[]string) {
+		if v := getenv(name); v != "" {
+			*dst = splitList(v)
+		}
+	}
+	integer := func(name string, dst *int) {
+		if v := getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, v, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v := getenv(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, v, err))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	str("HTTP_ADDR", &c.HTTP.Addr)
+	list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+	list("CORS_ALLOWED_METHODS", &c.CORS.AllowedMethods)
+	list("CORS_ALLOWED_HEADERS", &c.CORS.AllowedHeaders)
+	list("CORS_EXPOSED_HEADERS", &c.CORS.ExposedHeaders)
+	boolean("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	duration("CORS_MAX_AGE", &c.CORS.MaxAge)
+	duration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout)
+	duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	str("LOG_LEVEL", &c.Log.Level)
+	str("LOG_FORMAT", &c.Log.Format)
+	if v := getenv("LOG_COMPONENTS"); v != "" {
+		components, err := parseComponentLevels(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_COMPONENTS %q: %w", v, err))
+		}
+		c.Log.Components = components
+	}
+	str("LOG_FILE", &c.Log.File)
+	integer("LOG_FILE_MAX_SIZE_MB", &c.Log.FileMaxSizeMB)
+	integer("LOG_FILE_MAX_BACKUPS", &c.Log.FileMaxBackups)
+	str("LOG_ADMIN_TOKEN", &c.Log.AdminToken)
+	boolean("LOG_REDACT", &c.Log.Redact.Enabled)
+	list("LOG_REDACT_KEYS", &c.Log.Redact.Keys)
+	list("LOG_REDACT_PATTERNS", &c.Log.Redact.Patterns)
+	str("LOG_REDACT_MODE", &c.Log.Redact.Mode)
+	str("LOG_REDACT_HASH_SALT", &c.Log.Redact.HashSalt)
+
+	str("STORAGE_DRIVER", &c.Storage.Driver)
+	str("SQLITE_PATH", &c.Storage.SQLitePath)
+	boolean("DB_MIGRATE_ON_START", &c.Storage.MigrateOnStart)
+	boolean("DB_SEED", &c.Storage.Seed)
+
+	str("DB_HOST", &c.Postgres.Host)
+	integer("DB_PORT", &c.Postgres.Port)
+	str("DB_USER", &c.Postgres.User)
+	str("DB_PASSWORD", &c.Postgres.Password)
+	str("DB_PASSWORD_FILE", &c.Postgres.PasswordFile)
+	str("DB_NAME", &c.Postgres.Name)
+	str("DB_SSLMODE", &c.Postgres.SSLMode)
+	integer("DB_MAX_OPEN_CONNS", &c.Postgres.MaxOpenConns)
+	integer("DB_MAX_IDLE_CONNS", &c.Postgres.MaxIdleConns)
+	duration("DB_CONN_MAX_LIFETIME", &c.Postgres.ConnMaxLifetime)
+	duration("DB_CONN_MAX_IDLE_TIME", &c.Postgres.ConnMaxIdleTime)
+	duration("DB_CONNECT_TIMEOUT", &c.Postgres.ConnectTimeout)
+	duration("DB_CONNECT_BACKOFF", &c.Postgres.ConnectBackoff)
+	duration("DB_CONNECT_MAX_BACKOFF", &c.Postgres.ConnectMaxBackoff)
+
+	duration("IDEMPOTENCY_TTL", &c.Idempotency.TTL)
+	duration("HEALTH_CHECK_TIMEOUT", &c.Health.CheckTimeout)
+
+	str("TRACING_EXPORTER", &c.Tracing.Exporter)
+	str("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	str("TRACING_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	boolean("TRACING_OTLP_INSECURE", &c.Tracing.OTLPInsecure)
+	if v := getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid TRACING_SAMPLE_RATIO %q: %w", v, err))
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+
+	boolean("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	str("RATE_LIMIT_KEY", &c.RateLimit.Key)
+	list("RATE_LIMIT_TRUSTED_PROXIES", &c.RateLimit.TrustedProxies)
+	for _, g := range c.RateLimit.groups() {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(g.name)
+		integer(prefix+"_REQUESTS", &g.group.Requests)
+		duration(prefix+"_PER", &g.group.Per)
+		integer(prefix+"_BURST", &g.group.Burst)
+	}
+
+	str("I18N_DEFAULT_LANGUAGE", &c.I18n.DefaultLanguage)
+	return errors.Join(errs...)
+}
+
+// flagValues holds the command-line overrides. Only flags that were given
+// on the command line are applied.
+type flagValues struct {
+	httpAddr, corsOrigins, logLevel        string
+	logFormat                              string
+	driver, sqlitePath                     string
+	migrateOnStart, seed                   bool
+	dbHost, dbUser, dbPasswordFile, dbName string
+	dbPort, dbMaxOpenConns                 int
+	dbConnectTimeout                       time.Duration
+	tracingExporter                        string
+	rateLimit                              bool
+	shutdownTimeout, idempotencyTTL        time.Duration
+}
+
+func bindFlags(fs *flag.FlagSet) *flagValues {
+	f := &flagValues{}
+	fs.StringVar(&f.httpAddr, "http-addr", "", "address to listen on, e.g. :8080")
+	fs.StringVar(&f.corsOrigins, "cors-origins", "", "comma separated origins allowed by CORS")
+	fs.StringVar(&f.logLevel, "log-level", "", "DEBUG, INFO, WARN or ERROR")
+	fs.StringVar(&f.logFormat, "log-format", "", "log format: json or text")
+	fs.StringVar(&f.driver, "storage", "", "storage driver: postgres, sqlite or memory")
+	fs.StringVar(&f.sqlitePath, "sqlite-path", "", "SQLite database file")
+	fs.BoolVar(&f.migrateOnStart, "migrate-on-start", false, "apply pending migrations on start")
+	fs.BoolVar(&f.seed, "seed", false, "load demo data on start")
+	fs.StringVar(&f.dbHost, "db-host", "", "Postgres host")
+	fs.IntVar(&f.dbPort, "db-port", 0, "Postgres port")
+	fs.StringVar(&f.dbUser, "db-user", "", "Postgres user")
+	fs.StringVar(&f.dbPasswordFile, "db-password-file", "", "file holding the Postgres password")
+	fs.StringVar(&f.dbName, "db-name", "", "Postgres database")
+	fs.IntVar(&f.dbMaxOpenConns, "db-max-open-conns", 0, "Postgres pool size, 0 for unlimited")
+	fs.DurationVar(&f.dbConnectTimeout, "db-connect-timeout", 0, "how long to retry connecting to Postgres on start")
+	fs.DurationVar(&f.shutdownTimeout, "shutdown-timeout", 0, "how long to wait for in-flight requests on shutdown")
+	fs.DurationVar(&f.idempotencyTTL, "idempotency-ttl", 0, "how long Idempotency-Key responses are kept")
+	fs.StringVar(&f.tracingExporter, "tracing", "", "trace exporter: none, stdout or otlp")
+	fs.BoolVar(&f.rateLimit, "rate-limit", false, "limit the request rate per client")
+	return f
+}
+
+func (f *flagValues) apply(fs *flag.FlagSet, c *Config) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "http-addr":
+			c.HTTP.Addr = f.httpAddr
+		case "cors-origins":
+			c.CORS.AllowedOrigins = splitList(f.corsOrigins)
+		case "log-level":
+			c.Log.Level = f.logLevel
+		case "log-format":
+			c.Log.Format = f.logFormat
+		case "storage":
+			c.Storage.Driver = f.driver
+		case "sqlite-path":
+			c.Storage.SQLitePath = f.sqlitePath
+		case "migrate-on-start":
+			c.Storage.MigrateOnStart = f.migrateOnStart
+		case "seed":
+			c.Storage.Seed = f.seed
+		case "db-host":
+			c.Postgres.Host = f.dbHost
+		case "db-port":
+			c.Postgres.Port = f.dbPort
+		case "db-user":
+			c.Postgres.User = f.dbUser
+		case "db-password-file":
+			c.Postgres.PasswordFile = f.dbPasswordFile
+		case "db-name":
+			c.Postgres.Name = f.dbName
+		case "db-max-open-conns":
+			c.Postgres.MaxOpenConns = f.dbMaxOpenConns
+		case "db-connect-timeout":
+			c.Postgres.ConnectTimeout = f.dbConnectTimeout
+		case "shutdown-timeout":
+			c.HTTP.ShutdownTimeout = f.shutdownTimeout
+		case "idempotency-ttl":
+			c.Idempotency.TTL = f.idempotencyTTL
+		case "tracing":
+			c.Tracing.Exporter = f.tracingExporter
+		case "rate-limit":
+			c.RateLimit.Enabled = f.rateLimit
+		}
+	})
+}
+
+func (c *Config) resolveSecrets() error {
+	if c.Postgres.PasswordFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.Postgres.PasswordFile)
+	if err != nil {
+		return fmt.Errorf("read postgres password file: %w", err)
+	}
+	c.Postgres.Password = strings.TrimRight(string(data), "\r\n")
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		add("http.addr %q: %v", c.HTTP.Addr, err)
+	}
+	c.CORS.validate(add)
+
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"read_timeout", c.HTTP.ReadTimeout},
+		{"write_timeout", c.HTTP.WriteTimeout},
+		{"idle_timeout", c.HTTP.IdleTimeout},
+		{"shutdown_timeout", c.HTTP.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			add("http.%s must be positive", t.name)
+		}
+	}
+
+	c.Log.validate(add)
+
+	switch c.Storage.Driver {
+	case "postgres":
+		if c.Postgres.Host == "" || c.Postgres.User == "" || c.Postgres.Name == "" {
+			add("postgres: host, user and name are required")
+		}
+		if c.Postgres.Port <= 0 || c.Postgres.Port > 65535 {
+			add("postgres.port %d: out of range", c.Postgres.Port)
+		}
+		switch c.Postgres.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			add("postgres.sslmode %q is not a libpq sslmode", c.Postgres.SSLMode)
+		}
+		c.Postgres.validatePool(add)
+	case "sqlite":
+		if c.Storage.SQLitePath == "" {
+			add("storage.sqlite_path is required for the sqlite driver")
+		}
+	case "memory":
+	default:
+		add("storage.driver %q: want postgres, sqlite or memory", c.Storage.Driver)
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		add("idempotency.ttl must be positive")
+	}
+	if c.Health.CheckTimeout <= 0 {
+		add("health.check_timeout must be positive")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			add("tracing.otlp_endpoint is required for the otlp exporter")
+		}
+	default:
+		add("tracing.exporter %q: want none, stdout or otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio %v: want a value from 0 to 1", c.Tracing.SampleRatio)
+	}
+	c.RateLimit.validate(add)
+	if !i18n.Supported(c.I18n.DefaultLanguage) {
+		add("i18n.default_language %q: want %s", c.I18n.DefaultLanguage, strings.Join(i18n.Languages, " or "))
+	}
+	return errors.Join(errs...)
+}
+
+func (c CORS) validate(add func(format string, args ...any)) {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				add("cors: allow_credentials cannot be combined with the * origin")
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			add("cors.allowed_origins: %q has more than one wildcard", origin)
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			add("cors.allowed_origins: %q is not an origin like https://example.com or https://*.example.com", origin)
+		}
+	}
+	if len(c.AllowedMethods) == 0 {
+		add("cors.allowed_methods must not be empty")
+	}
+	if c.MaxAge < 0 {
+		add("cors.max_age must not be negative")
+	}
+}
+
+type namedRateLimitGroup struct {
+	name  string
+	group *RateLimitGroup
+}
+
+func (l Log) validate(add func(format string, args ...any)) {
+	if !validLevel(l.Level) {
+		add("log.level %q: want DEBUG, INFO, WARN or ERROR", l.Level)
+	}
+	switch l.Format {
+	case "json", "text":
+	default:
+		add("log.format %q: want json or text", l.Format)
+	}
+	for component, level := range l.Components {
+		if component == "" || !validLevel(level) {
+			add("log.components: %q: %q is not a component level", component, level)
+		}
+	}
+	if l.File != "" && l.FileMaxSizeMB <= 0 {
+		add("log.file_max_size_mb must be positive")
+	}
+	if l.FileMaxBackups < 0 {
+		add("log.file_max_backups must not be negative")
+	}
+
+	switch l.Redact.Mode {
+	case "mask":
+	case "hash":
+		if l.Redact.Enabled && l.Redact.HashSalt == "" {
+			add("log.redact.hash_salt is required for the hash mode")
+		}
+	default:
+		add("log.redact.mode %q: want mask or hash", l.Redact.Mode)
+	}
+	for _, p := range l.Redact.Patterns {
+		if _, builtin := logger.BuiltinPatterns[p]; builtin {
+			continue
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			add("log.redact.patterns: %q is neither a builtin pattern nor a regular expression: %v", p, err)
+		}
+	}
+}
+
+func validLevel(level string) bool {
+	switch strings.ToUpper(level) {
+	case "DEBUG", "INFO", "WARN", "ERROR":
+		return true
+	}
+	return false
+}
+
+// parseComponentLevels reads RoomUsecase=DEBUG,Migrator=WARN.
+func parseComponentLevels(v string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, pair := range splitList(v) {
+		component, level, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want component=LEVEL", pair)
+		}
+		levels[strings.TrimSpace(component)] = strings.TrimSpace(level)
+	}
+	return levels, nil
+}
+
+// groups lists the groups under the names used in settings and routes.
+func (r *RateLimit) groups() []namedRateLimitGroup {
+	return []namedRateLimitGroup{
+		{"booking", &r.Booking},
+		{"search", &r.Search},
+		{"default", &r.Default},
+	}
+}
+
+func (r RateLimit) validate(add func(format string, args ...any)) {
+	switch r.Key {
+	case "ip", "api_key", "user":
+	default:
+		add("rate_limit.key %q: want ip, api_key or user", r.Key)
+	}
+	for _, proxy := range r.TrustedProxies {
+		addr := proxy
+		if !strings.Contains(proxy, "/") {
+			addr += "/0"
+		}
+		if _, err := netip.ParsePrefix(addr); err != nil {
+			add("rate_limit.trusted_proxies: %q is not an address or CIDR range", proxy)
+		}
+	}
+	for _, g := range r.groups() {
+		if g.group.Requests <= 0 || g.group.Per <= 0 {
+			add("rate_limit.%s: requests and per must be positive", g.name)
+		}
+		if g.group.Burst < 0 {
+			add("rate_limit.%s.burst must not be negative", g.name)
+		}
+	}
+}
+
+func (p Postgres) validatePool(add func(format string, args ...any)) {
+	if p.MaxOpenConns < 0 || p.MaxIdleConns < 0 {
+		add("postgres.max_open_conns and max_idle_conns must not be negative")
+	}
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		add("postgres.max_idle_conns %d exceeds max_open_conns %d", p.MaxIdleConns, p.MaxOpenConns)
+	}
+	if p.ConnMaxLifetime < 0 || p.ConnMaxIdleTime < 0 {
+		add("postgres.conn_max_lifetime and conn_max_idle_time must not be negative")
+	}
+	if p.ConnectTimeout <= 0 || p.ConnectBackoff <= 0 {
+		add("postgres.connect_timeout and connect_backoff must be positive")
+	}
+	if p.ConnectMaxBackoff < p.ConnectBackoff {
+		add("postgres.connect_max_backoff must not be less than connect_backoff")
+	}
+}
+
+// DSN is the lib/pq connection string for c.
+func (p Postgres) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		dsnValue(p.Host), p.Port, dsnValue(p.User), dsnValue(p.Password), dsnValue(p.Name), dsnValue(p.SSLMode))
+}
+
+// dsnValue quotes v for a key=value connection string.
+func dsnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// Redacted returns a copy of c that is safe to print or log. The copy does
+// not share the component levels map with c, so editing it leaves c alone.
+func (c Config) Redacted() Config {
+	if c.Postgres.Password != "" {
+		c.Postgres.Password = redacted
+	}
+	if c.Log.AdminToken != "" {
+		c.Log.AdminToken = redacted
+	}
+	if c.Log.Redact.HashSalt != "" {
+		c.Log.Redact.HashSalt = redacted
+	}
+	c.Log.Components = maps.Clone(c.Log.Components)
+	return c
+}
+
+// YAML renders c in the config file format.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, rest, err := Load(nil, env(nil))
+
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, Default(), cfg)
+	assert.Empty(t, cfg.Postgres.Password, "no password is baked in")
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "hotel.yaml", `
+http:
+  addr: ":7000"
+cors:
+  allowed_origins: ["https://file.example"]
+storage:
+  driver: sqlite
+  sqlite_path: /var/lib/hotel.db
+idempotency:
+  ttl: 2h
+`)
+
+	cfg, rest, err := Load(
+		[]string{"-config", path, "-http-addr", ":9000", "migrate", "up"},
+		env(map[string]string{"HTTP_ADDR": ":8000", "STORAGE_DRIVER": "memory", "IDEMPOTENCY_TTL": "90m"}),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, rest)
+	assert.Equal(t, ":9000", cfg.HTTP.Addr, "flags beat env")
+	assert.Equal(t, "memory", cfg.Storage.Driver, "env beats the file")
+	assert.Equal(t, 90*time.Minute, cfg.Idempotency.TTL)
+	assert.Equal(t, "/var/lib/hotel.db", cfg.Storage.SQLitePath, "the file beats defaults")
+	assert.Equal(t, []string{"https://file.example"}, cfg.CORS.AllowedOrigins)
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, "hotel.yaml", "log:\n  level: DEBUG\n")
+
+	cfg, _, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG", cfg.Log.Level)
+}
+
+func TestLoad_RejectsUnknownFileKeys(t *testing.T) {
+	path := writeFile(t, "hotel.yaml", "http:\n  adr: \":1\"\n")
+
+	_, _, err := Load([]string{"-config", path}, env(nil))
+
+	assert.ErrorContains(t, err, "adr")
+}
+
+func TestLoad_PasswordFile(t *testing.T) {
+	secret := writeFile(t, "db_password", "s3cret pa'ss\n")
+
+	cfg, _, err := Load(nil, env(map[string]string{"DB_PASSWORD": "ignored", "DB_PASSWORD_FILE": secret}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret pa'ss", cfg.Postgres.Password)
+	assert.Contains(t, cfg.Postgres.DSN(), `password='s3cret pa\'ss'`)
+}
+
+func TestLoad_Validation(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{
+		"HTTP_ADDR":            "8080",
+		"STORAGE_DRIVER":       "oracle",
+		"LOG_LEVEL":            "LOUD",
+		"CORS_ALLOWED_ORIGINS": "localhost:3000",
+		"HTTP_WRITE_TIMEOUT":   "0s",
+		"TRACING_EXPORTER":     "jaeger",
+		"TRACING_SAMPLE_RATIO": "1.5",
+	}))
+
+	require.Error(t, err)
+	for _, want := range []string{"http.addr", "storage.driver", "log.level", "cors.allowed_origins", "http.write_timeout", "tracing.exporter", "tracing.sample_ratio"} {
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestLoad_CORS(t *testing.T) {
+	cfg, _, err := Load([]string{"-cors-origins", "https://*.example.com,http://localhost:3000"}, env(map[string]string{
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"CORS_MAX_AGE":           "1h",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://*.example.com", "http://localhost:3000"}, cfg.CORS.AllowedOrigins)
+	assert.True(t, cfg.CORS.AllowCredentials)
+	assert.Equal(t, time.Hour, cfg.CORS.MaxAge)
+
+	_, _, err = Load(nil, env(map[string]string{
+		"CORS_ALLOWED_ORIGINS":   "*,https://*.*.example.com",
+		"CORS_ALLOW_CREDENTIALS": "true",
+	}))
+	assert.ErrorContains(t, err, "allow_credentials cannot be combined")
+	assert.ErrorContains(t, err, "more than one wildcard")
+}
+
+func TestLoad_RateLimit(t *testing.T) {
+	cfg, _, err := Load(nil, env(map[string]string{
+		"RATE_LIMIT_KEY":             "api_key",
+		"RATE_LIMIT_TRUSTED_PROXIES": "10.0.0.0/8,192.0.2.1",
+		"RATE_LIMIT_BOOKING_BURST":   "3",
+		"RATE_LIMIT_SEARCH_PER":      "10s",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "api_key", cfg.RateLimit.Key)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.RateLimit.TrustedProxies)
+	assert.Equal(t, 3, cfg.RateLimit.Booking.Burst)
+	assert.Equal(t, 10*time.Second, cfg.RateLimit.Search.Per)
+
+	_, _, err = Load(nil, env(map[string]string{
+		"RATE_LIMIT_KEY":              "cookie",
+		"RATE_LIMIT_TRUSTED_PROXIES":  "proxy.local",
+		"RATE_LIMIT_DEFAULT_REQUESTS": "0",
+	}))
+	assert.ErrorContains(t, err, "rate_limit.key")
+	assert.ErrorContains(t, err, "rate_limit.trusted_proxies")
+	assert.ErrorContains(t, err, "rate_limit.default")
+}
+
+func TestLoad_LogSettings(t *testing.T) {
+	cfg, _, err := Load([]string{"-log-format", "text"}, env(map[string]string{
+		"LOG_COMPONENTS":  "RoomUsecase=DEBUG, Migrator=warn",
+		"LOG_FILE":        "/var/log/hotel.log",
+		"LOG_ADMIN_TOKEN": "s3cret",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "text", cfg.Log.Format)
+	assert.Equal(t, map[string]string{"RoomUsecase": "DEBUG", "Migrator": "warn"}, cfg.Log.Components)
+	assert.Equal(t, "/var/log/hotel.log", cfg.Log.File)
+
+	out, err := cfg.Redacted().YAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "s3cret")
+
+	_, _, err = Load(nil, env(map[string]string{
+		"LOG_FORMAT":           "xml",
+		"LOG_COMPONENTS":       "RoomUsecase=LOUD",
+		"LOG_FILE":             "hotel.log",
+		"LOG_FILE_MAX_SIZE_MB": "0",
+	}))
+	assert.ErrorContains(t, err, "log.format")
+	assert.ErrorContains(t, err, "log.components")
+	assert.ErrorContains(t, err, "log.file_max_size_mb")
+
+	_, _, err = Load(nil, env(map[string]string{"LOG_COMPONENTS": "RoomUsecase"}))
+	assert.ErrorContains(t, err, "LOG_COMPONENTS")
+}
+
+func TestLoad_Redact(t *testing.T) {
+	cfg, _, err := Load(nil, env(map[string]string{
+		"LOG_REDACT_KEYS":      "guest_name,email",
+		"LOG_REDACT_PATTERNS":  `email,card-\d{4}`,
+		"LOG_REDACT_MODE":      "hash",
+		"LOG_REDACT_HASH_SALT": "pepper",
+	}))
+	require.NoError(t, err)
+	assert.True(t, cfg.Log.Redact.Enabled, "redaction is on by default")
+	assert.Equal(t, []string{"guest_name", "email"}, cfg.Log.Redact.Keys)
+	assert.Equal(t, []string{"email", `card-\d{4}`}, cfg.Log.Redact.Patterns)
+
+	out, err := cfg.Redacted().YAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "pepper")
+
+	_, _, err = Load(nil, env(map[string]string{"LOG_REDACT_MODE": "blur", "LOG_REDACT_PATTERNS": "email,("}))
+	assert.ErrorContains(t, err, "log.redact.mode")
+	assert.ErrorContains(t, err, "log.redact.patterns")
+}
+
+func TestLoad_RedactHashNeedsSalt(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{"LOG_REDACT_MODE": "hash"}))
+	assert.ErrorContains(t, err, "log.redact.hash_salt is required for the hash mode")
+
+	_, _, err = Load(nil, env(map[string]string{"LOG_REDACT_MODE": "hash", "LOG_REDACT": "false"}))
+	assert.NoError(t, err, "the salt is not needed while redaction is off")
+}
+
+func TestLoad_DefaultLanguage(t *testing.T) {
+	cfg, _, err := Load(nil, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "en", cfg.I18n.DefaultLanguage)
+
+	cfg, _, err = Load(nil, env(map[string]string{"I18N_DEFAULT_LANGUAGE": "ru"}))
+	require.NoError(t, err)
+	assert.Equal(t, "ru", cfg.I18n.DefaultLanguage)
+
+	_, _, err = Load(nil, env(map[string]string{"I18N_DEFAULT_LANGUAGE": "de"}))
+	assert.ErrorContains(t, err, `i18n.default_language "de": want en or ru`)
+}
+
+func TestLoad_PoolSettings(t *testing.T) {
+	cfg, _, err := Load([]string{"-db-max-open-conns", "10"}, env(map[string]string{
+		"DB_MAX_IDLE_CONNS":  "4",
+		"DB_CONNECT_TIMEOUT": "1m",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.Postgres.MaxOpenConns)
+	assert.Equal(t, 4, cfg.Postgres.MaxIdleConns)
+	assert.Equal(t, time.Minute, cfg.Postgres.ConnectTimeout)
+
+	_, _, err = Load(nil, env(map[string]string{
+		"DB_MAX_OPEN_CONNS":      "5",
+		"DB_MAX_IDLE_CONNS":      "10",
+		"DB_CONNECT_MAX_BACKOFF": "1ms",
+	}))
+	assert.ErrorContains(t, err, "max_idle_conns 10 exceeds max_open_conns 5")
+	assert.ErrorContains(t, err, "connect_max_backoff")
+}
+
+func TestLoad_BadEnvValue(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{"DB_SEED": "sometimes"}))
+
+	assert.ErrorContains(t, err, "DB_SEED")
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.Password = "hunter2"
+
+	out, err := cfg.Redacted().YAML()
+
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "hunter2")
+	assert.Contains(t, string(out), "REDACTED")
+	assert.Equal(t, "hunter2", cfg.Postgres.Password, "the original is left alone")
+
+	cfg.Log.Components = map[string]string{"RoomUsecase": "DEBUG"}
+	cfg.Redacted().Log.Components["RoomUsecase"] = "ERROR"
+	assert.Equal(t, "DEBUG", cfg.Log.Components["RoomUsecase"], "the copy does not share the level map")
+
+	path := writeFile(t, "printed.yaml", string(out))
+	reloaded, _, err := Load([]string{"-config", path}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Idempotency.TTL, reloaded.Idempotency.TTL, "printed config loads back")
+	assert.True(t, strings.Contains(string(out), "ttl: 24h0m0s"), string(out))
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/delivery/handlers/helpers"
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/metrics"
+	"golangHotelProject/internal/usecase"
+	"net/http"
+)
+
+// @Summary batch operations
+// @Tags batch
+// @Description run up to 100 create/patch/delete operations on rooms and bookings.
+// @Description With "atomic": true all operations share one transaction and the response status is the failing
+// @Description operation's status; otherwise every operation commits on its own and gets its own status.
+// @ID batch
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "makes retries of this request safe"
+// @Param input body dto.BatchRequest true "operations"
+// @Success 200 {object} dto.BatchResponse "per-operation results"
+// @Failure 400 {object} dto.BatchResponse "Invalid JSON or an atomic batch failed validation"
+// @Failure 409 {object} dto.BatchResponse "An atomic batch hit a conflict"
+// @Failure 412 {object} dto.BatchResponse "An atomic batch hit a stale version"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /Batch [post]
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	log := helpers.ReqLogger(r, "batch.execute")
+
+	if r.Method != http.MethodPost {
+		log.Warn(
+			"method not allowed",
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.Error("error closing request body", "err", err)
+		}
+	}()
+
+	var req dto.BatchRequest
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&req); err != nil {
+		log.Warn("invalid json", "error", err)
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	log.Info("executing batch", "operations", len(req.Operations), "atomic", req.Atomic)
+
+	results, committed, err := h.batch.Execute(r.Context(), req.Operations, req.Atomic)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "execute batch", err)
+		return
+	}
+
+	status := http.StatusOK
+	response := dto.BatchResponse{
+		Atomic:    req.Atomic,
+		Committed: committed,
+		Results:   make([]dto.BatchItemResult, len(results)),
+	}
+	lang := i18n.FromContext(r.Context())
+	for i, res := range results {
+		item := dto.BatchItemResult{Index: res.Index, Result: res.Value}
+		switch {
+		case res.Err == nil && res.Created:
+			item.Status = http.StatusCreated
+		case res.Err == nil:
+			item.Status = http.StatusOK
+		case usecase.IsBatchAbortedErr(res.Err):
+			item.Status = http.StatusFailedDependency
+			body := helpers.ErrorBody(lang, res.Err)
+			item.Code, item.Error = body.Code, body.Error
+		default:
+			item.Status = helpers.StatusForUsecaseError(res.Err)
+			body := helpers.ErrorBody(lang, res.Err)
+			item.Code, item.Error, item.Fields = body.Code, body.Error, body.Fields
+			metrics.RecordUsecaseError(r.Context(), usecase.ErrorKind(res.Err))
+			if req.Atomic {
+				status = item.Status
+			}
+		}
+		response.Results[i] = item
+	}
+
+	log.Info("batch executed", "operations", len(results), "committed", committed, "status", status)
+
+	if err := helpers.WriteJSON(w, status, response); err != nil {
+		log.Error("JSON encode error", "error", err)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	log.Info("response sent", "status", status)
+}
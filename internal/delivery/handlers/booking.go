@@ -1,38 +1,32 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"golangHotelProject/internal/delivery/handlers/dto"
 	"golangHotelProject/internal/delivery/handlers/helpers"
 	"golangHotelProject/internal/model"
-	"golangHotelProject/internal/usecase"
 	"net/http"
 	"strconv"
 )
 
-var bookingUC *usecase.BookingUsecase
-
-func InitBookingDependencies(uc *usecase.BookingUsecase) error {
-	if uc == nil {
-		return fmt.Errorf("nil usecase")
-	}
-	bookingUC = uc
-	return nil
-}
-
 // CreateBooking creates a new booking
 // @Summary Create a new booking
 // @Description Create a booking for a room
 // @Tags bookings
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "makes retries of this request safe"
 // @Param booking body model.Booking true "Booking object"
-// @Success 201 {object} string
+// @Success 201 {object} model.Booking
+// @Header 201 {string} Location "URL of the created booking"
+// @Header 201 {string} ETag "booking version"
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /CreateBooking [post]
-func CreateBooking(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "booking.create")
 
 	if r.Method != http.MethodPost {
@@ -41,7 +35,7 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		helpers.WriteTextError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -58,26 +52,28 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&NewBooking)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
-	log.Info("creating booking", "booking_id", NewBooking.ID)
+	log.Info("creating booking", "room_id", NewBooking.RoomID, "guest_id", NewBooking.GuestID)
 
-	if err := bookingUC.CreateBooking(r.Context(), NewBooking); err != nil {
-		helpers.HandleUsecaseError(w, log, "create booking", err)
+	created, err := h.bookings.CreateBooking(r.Context(), NewBooking)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "create booking", err)
 		return
 	}
 
-	log.Info("booking created", "booking_id", NewBooking.ID)
+	log.Info("booking created", "booking_id", created.ID)
 
-	response := "Booking created"
-	if err := helpers.WriteJSON(w, http.StatusCreated, response); err != nil {
-		log.Error("JSON encode error", "error", err, "booking_id", NewBooking.ID)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+	w.Header().Set("Location", fmt.Sprintf("/ReadBookingByID?id=%d", created.ID))
+	helpers.SetETag(w, created.Version)
+	if err := helpers.WriteJSON(w, http.StatusCreated, created); err != nil {
+		log.Error("JSON encode error", "error", err, "booking_id", created.ID)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
-	log.Info("response sent", "status", http.StatusCreated, "booking_id", NewBooking.ID)
+	log.Info("response sent", "status", http.StatusCreated, "booking_id", created.ID)
 }
 
 // ReadBookingByID returns booking by ID
@@ -87,10 +83,11 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id query int true "Booking ID"
 // @Success 200 {object} map[string]model.Booking
+// @Header 200 {string} ETag "booking version"
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /ReadBookingByID [get]
-func ReadBookingByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ReadBookingByID(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "booking.readByID")
 
 	if r.Method != http.MethodGet {
@@ -99,39 +96,40 @@ func ReadBookingByID(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		helpers.WriteTextError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
 		log.Warn("missing id")
-		helpers.WriteTextError(w, http.StatusBadRequest, "id input is clear")
+		helpers.WriteError(w, r, http.StatusBadRequest, "missing_id")
 		return
 	}
 
 	idInt, err := strconv.Atoi(idStr)
 	if err != nil || idInt <= 0 {
 		log.Warn("invalid id", "id", idStr)
-		helpers.WriteTextError(w, http.StatusBadRequest, "pars error")
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
 	log.Info("reading booking", "booking_id", idInt)
 
-	book, err := bookingUC.ReadByIDUsecase(r.Context(), idInt)
+	book, err := h.bookings.ReadByIDUsecase(r.Context(), idInt)
 	if err != nil {
-		helpers.HandleUsecaseError(w, log, "reading booking", err)
+		helpers.HandleUsecaseError(w, r, log, "reading booking", err)
 		return
 	}
 
 	log.Info("booking retrieved", "booking_id", idInt)
 
+	helpers.SetETag(w, book.Version)
 	text := fmt.Sprintf("column id: %d", idInt)
 	response := map[string]model.Booking{text: book}
 	if err := helpers.WriteJSON(w, http.StatusOK, response); err != nil {
 		log.Error("JSON encode error", "error", err, "booking_id", idInt)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "booking_id", idInt)
@@ -143,12 +141,17 @@ func ReadBookingByID(w http.ResponseWriter, r *http.Request) {
 // @Tags bookings
 // @Accept json
 // @Produce json
+// @Param If-Match header string true "ETag of the booking version being patched"
 // @Param booking body dto.BookingPatch true "Booking object with updates"
 // @Success 200 {object} string
+// @Header 200 {string} ETag "new booking version"
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /PatchBookingByID [patch]
-func PatchBookingByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PatchBookingByID(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "booking.patch")
 
 	if r.Method != http.MethodPatch {
@@ -157,7 +160,7 @@ func PatchBookingByID(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		helpers.WriteTextError(w, http.StatusMethodNotAllowed, "method not allowed")
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -168,31 +171,45 @@ func PatchBookingByID(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	version, err := helpers.IfMatchVersion(r)
+	if err != nil {
+		helpers.HandleIfMatchError(w, r, log, err)
+		return
+	}
+
 	var patch dto.BookingPatch
 
-	err := json.NewDecoder(r.Body).Decode(&patch)
+	err = json.NewDecoder(r.Body).Decode(&patch)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
-	log.Info("patching booking", "booking_id", patch.ID)
+	if patch.ID != nil {
+		log.Info("patching booking", "booking_id", *patch.ID, "version", version)
+	} else {
+		log.Info("patching booking", "version", version)
+	}
 
-	if err := bookingUC.PatchBookingByID(r.Context(), patch); err != nil {
-		helpers.HandleUsecaseError(w, log, "patch booking", err)
+	newVersion, err := h.bookings.PatchBookingByID(r.Context(), version, patch)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "patch booking", err)
 		return
 	}
 
-	log.Info("booking patched", "booking_id", patch.ID)
+	// The usecase has checked that the ID is set.
+	id := *patch.ID
+	log.Info("booking patched", "booking_id", id, "version", newVersion)
 
-	response := fmt.Sprintf("column id: %d", patch.ID)
+	helpers.SetETag(w, newVersion)
+	response := fmt.Sprintf("column id: %d", id)
 	if err := helpers.WriteJSON(w, http.StatusOK, response); err != nil {
-		log.Error("JSON encode error", "error", err, "booking_id", patch.ID)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		log.Error("JSON encode error", "error", err, "booking_id", id)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
-	log.Info("response sent", "status", http.StatusOK, "booking_id", patch.ID)
+	log.Info("response sent", "status", http.StatusOK, "booking_id", id)
 }
 
 // GetFilteredBookings returns filtered bookings
@@ -206,7 +223,7 @@ func PatchBookingByID(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /GetFilteredBookings [get]
-func GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "booking.getFiltered")
 
 	if r.Method != http.MethodGet {
@@ -215,7 +232,7 @@ func GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		helpers.WriteTextError(w, http.StatusMethodNotAllowed, "method not allowed")
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -230,7 +247,7 @@ func GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&filter)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "Invalid JSON format: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
@@ -238,33 +255,33 @@ func GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
 
 	if len(filter) == 0 {
 		log.Info("getting all bookings")
-		bookings, err := bookingUC.GetList(r.Context())
+		bookings, err := h.bookings.GetList(r.Context())
 
 		if err != nil {
-			helpers.HandleUsecaseError(w, log, "get all bookings", err)
+			helpers.HandleUsecaseError(w, r, log, "get all bookings", err)
 			return
 		}
 
 		log.Info("bookings retrieved", "count", len(bookings))
 		if err := helpers.WriteJSON(w, http.StatusOK, bookings); err != nil {
 			log.Error("JSON encode error", "error", err)
-			helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error")
+			helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 			return
 		}
 		log.Info("response sent", "status", http.StatusOK, "count", len(bookings))
 		return
 	}
 
-	responses, err := bookingUC.GetFilteredBookings(r.Context(), filter)
+	responses, err := h.bookings.GetFilteredBookings(r.Context(), filter)
 	if err != nil {
-		helpers.HandleUsecaseError(w, log, "get filtered bookings", err)
+		helpers.HandleUsecaseError(w, r, log, "get filtered bookings", err)
 		return
 	}
 
 	log.Info("filtered bookings retrieved", "filter", filter, "count", len(responses))
 	if err := helpers.WriteJSON(w, http.StatusOK, responses); err != nil {
 		log.Error("JSON encode error", "error", err, "filter", filter)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "filter", filter, "count", len(responses))
@@ -276,12 +293,16 @@ func GetFilteredBookings(w http.ResponseWriter, r *http.Request) {
 // @Tags bookings
 // @Accept json
 // @Produce json
+// @Param If-Match header string true "ETag of the booking version being removed"
 // @Param id body int true "Booking ID"
 // @Success 200 {string} string
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /RemoveBooking [delete]
-func RemoveBooking(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RemoveBooking(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "booking.remove")
 
 	if r.Method != http.MethodDelete {
@@ -290,7 +311,7 @@ func RemoveBooking(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		helpers.WriteTextError(w, http.StatusMethodNotAllowed, "method not allowed")
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -301,19 +322,25 @@ func RemoveBooking(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	version, err := helpers.IfMatchVersion(r)
+	if err != nil {
+		helpers.HandleIfMatchError(w, r, log, err)
+		return
+	}
+
 	var removingBookingID int
 
-	err := json.NewDecoder(r.Body).Decode(&removingBookingID)
+	err = json.NewDecoder(r.Body).Decode(&removingBookingID)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
-	log.Info("removing booking", "booking_id", removingBookingID)
+	log.Info("removing booking", "booking_id", removingBookingID, "version", version)
 
-	if err = bookingUC.RemoveBooking(r.Context(), removingBookingID); err != nil {
-		helpers.HandleUsecaseError(w, log, "remove booking", err)
+	if err = h.bookings.RemoveBooking(r.Context(), removingBookingID, version); err != nil {
+		helpers.HandleUsecaseError(w, r, log, "remove booking", err)
 		return
 	}
 
@@ -322,8 +349,94 @@ func RemoveBooking(w http.ResponseWriter, r *http.Request) {
 	removedBooking := fmt.Sprintf("Removed Booking id: %d", removingBookingID)
 	if err := helpers.WriteJSON(w, http.StatusOK, removedBooking); err != nil {
 		log.Error("JSON encode error", "error", err, "booking_id", removingBookingID)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "booking_id", removingBookingID)
 }
+
+// CheckIn starts the stay of a booking
+// @Summary Check a guest in
+// @Description Mark the booking active and its room occupied in one transaction
+// @Tags bookings
+// @Produce json
+// @Param id query int true "Booking ID"
+// @Param If-Match header string true "ETag of the booking version being checked in"
+// @Success 200 {object} model.Booking
+// @Header 200 {string} ETag "new booking version"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /CheckIn [post]
+func (h *Handler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	changeStay(w, r, "booking.checkIn", h.bookings.CheckIn)
+}
+
+// CheckOut ends the stay of a booking
+// @Summary Check a guest out
+// @Description Close the booking, free its room and flag the room for cleaning in one transaction
+// @Tags bookings
+// @Produce json
+// @Param id query int true "Booking ID"
+// @Param If-Match header string true "ETag of the booking version being checked out"
+// @Success 200 {object} model.Booking
+// @Header 200 {string} ETag "new booking version"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /CheckOut [post]
+func (h *Handler) CheckOut(w http.ResponseWriter, r *http.Request) {
+	changeStay(w, r, "booking.checkOut", h.bookings.CheckOut)
+}
+
+func changeStay(w http.ResponseWriter, r *http.Request, name string, change func(ctx context.Context, id, version int) (model.Booking, error)) {
+	log := helpers.ReqLogger(r, name)
+
+	if r.Method != http.MethodPost {
+		log.Warn(
+			"method not allowed",
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		log.Warn("invalid id", "id", idStr)
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	version, err := helpers.IfMatchVersion(r)
+	if err != nil {
+		helpers.HandleIfMatchError(w, r, log, err)
+		return
+	}
+
+	log.Info("changing stay", "booking_id", id, "version", version)
+
+	booking, err := change(r.Context(), id, version)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, name, err)
+		return
+	}
+
+	log.Info("stay changed", "booking_id", id, "version", booking.Version)
+
+	helpers.SetETag(w, booking.Version)
+	if err := helpers.WriteJSON(w, http.StatusOK, booking); err != nil {
+		log.Error("JSON encode error", "error", err, "booking_id", id)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	log.Info("response sent", "status", http.StatusOK, "booking_id", id)
+}
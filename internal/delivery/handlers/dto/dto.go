@@ -1,6 +1,10 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"golangHotelProject/internal/validation"
+	"time"
+)
 
 type RoomDTO struct {
 	ID             int     `json:"id" example:"1"`
@@ -41,13 +45,13 @@ type BookingPatch struct {
 	Status     *string    `json:"status,omitempty" example:"cancelled"`
 }
 
-type CreatingRoomResponse struct {
-	Message string `json:"message"`
-	RoomID  int    `json:"roomId"`
-}
-
+// ErrorResponse is the body of a failed request. Code is stable and meant
+// for programs; Error is its message in the language of the request. Fields
+// lists every broken validation rule when the request did not validate.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Code   string                  `json:"code" example:"validation_failed"`
+	Error  string                  `json:"error" example:"validation failed"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
 }
 
 type RoomPatchResponse struct {
@@ -57,3 +61,39 @@ type RoomPatchResponse struct {
 type RemoveRoomRequest struct {
 	RoomID int `json:"roomId"`
 }
+
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic" example:"true"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one create/patch/delete inside a batch. Data holds the
+// same body the single-item endpoint takes; patch and delete also need the
+// ID and the Version the client last saw.
+type BatchOperation struct {
+	Op       string          `json:"op" example:"create" enums:"create,patch,delete"`
+	Resource string          `json:"resource" example:"room" enums:"room,booking"`
+	ID       int             `json:"id,omitempty" example:"1"`
+	Version  int             `json:"version,omitempty" example:"1"`
+	Data     json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+type BatchItemResult struct {
+	Index  int                     `json:"index" example:"0"`
+	Status int                     `json:"status" example:"201"`
+	Code   string                  `json:"code,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
+	Result any                     `json:"result,omitempty"`
+}
+
+type BatchResponse struct {
+	Atomic    bool              `json:"atomic" example:"true"`
+	Committed bool              `json:"committed" example:"true"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+type VersionedID struct {
+	ID      int `json:"id" example:"1"`
+	Version int `json:"version,omitempty" example:"2"`
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"golangHotelProject/internal/usecase"
+)
+
+// Handler serves the HTTP API on top of the usecases it is built with.
+type Handler struct {
+	rooms    *usecase.RoomUsecase
+	bookings *usecase.BookingUsecase
+	batch    *usecase.BatchUsecase
+}
+
+func NewHandler(rooms *usecase.RoomUsecase, bookings *usecase.BookingUsecase, batch *usecase.BatchUsecase) (*Handler, error) {
+	if rooms == nil || bookings == nil || batch == nil {
+		return nil, fmt.Errorf("nil usecase")
+	}
+	return &Handler{rooms: rooms, bookings: bookings, batch: batch}, nil
+}
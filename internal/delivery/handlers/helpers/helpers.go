@@ -2,18 +2,33 @@ package helpers
 
 import (
 	"encoding/json"
+	"errors"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/logger"
+	"golangHotelProject/internal/metrics"
 	"golangHotelProject/internal/usecase"
+	"golangHotelProject/internal/validation"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+var (
+	ErrIfMatchMissing = errors.New("If-Match header is required")
+	ErrIfMatchInvalid = errors.New("If-Match must hold a single entity tag")
+)
+
+// ReqLogger is the logger of one handler call. It extends the request-scoped
+// logger, so its lines carry the request ID.
 func ReqLogger(r *http.Request, handler string) *slog.Logger {
-	return slog.Default().With(
+	return logger.FromContext(r.Context()).With(
 		"handler", handler,
 		"method", r.Method,
 		"path", r.URL.Path,
 		"remote", r.RemoteAddr,
-	)
+	).With(logger.TraceAttrs(r.Context())...)
 }
 
 func WriteJSON(w http.ResponseWriter, status int, v any) error {
@@ -27,20 +42,123 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return err
 }
 
-func WriteTextError(w http.ResponseWriter, status int, msg string) {
-	http.Error(w, msg, status)
+// WriteError answers with an ErrorResponse holding code and its message in
+// the language of the request.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code string, params ...any) {
+	body := dto.ErrorResponse{
+		Code:  code,
+		Error: i18n.Message(i18n.FromContext(r.Context()), code, code, params...),
+	}
+	if err := WriteJSON(w, status, body); err != nil {
+		logger.FromContext(r.Context()).Error("error writing error response", "code", code, "error", err)
+	}
+}
+
+// SetETag advertises the resource version as a strong entity tag.
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatchVersion extracts the version a client expects from If-Match. Weak
+// tags are accepted since the tag only ever carries the row version.
+func IfMatchVersion(r *http.Request) (int, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" {
+		return 0, ErrIfMatchMissing
+	}
+
+	tag := strings.TrimPrefix(raw, "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, ErrIfMatchInvalid
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, ErrIfMatchInvalid
+	}
+	return version, nil
+}
+
+// HandleIfMatchError answers a request whose If-Match header could not be used.
+func HandleIfMatchError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	logger.Info("precondition header error", "error", err)
+	if errors.Is(err, ErrIfMatchMissing) {
+		WriteError(w, r, http.StatusPreconditionRequired, "if_match_missing")
+		return
+	}
+	WriteError(w, r, http.StatusBadRequest, "if_match_invalid")
 }
 
-func HandleUsecaseError(w http.ResponseWriter, logger *slog.Logger, op string, err error) {
+// StatusForUsecaseError maps a usecase error to the HTTP status it answers with.
+func StatusForUsecaseError(err error) int {
 	switch {
 	case usecase.IsValidationErr(err):
-		logger.Info("validation error", "op", op, "error", err)
-		WriteTextError(w, http.StatusBadRequest, err.Error())
+		return http.StatusBadRequest
 	case usecase.IsConflictErr(err):
+		return http.StatusConflict
+	case usecase.IsNotFoundErr(err):
+		return http.StatusNotFound
+	case usecase.IsPreconditionErr(err):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// kindCodes are the envelope codes of usecase errors that carry no code of
+// their own, by usecase.ErrorKind.
+var kindCodes = map[string]string{
+	"validation":   "invalid_request",
+	"conflict":     "conflict",
+	"not_found":    "not_found",
+	"precondition": "precondition_failed",
+	"internal":     "internal_error",
+}
+
+// ErrorBody is the response body for a usecase error, with its messages in
+// lang. A validation error lists its broken rules in Fields.
+func ErrorBody(lang string, err error) dto.ErrorResponse {
+	if fields := validation.Fields(err); len(fields) > 0 {
+		localized := make([]validation.FieldError, len(fields))
+		for i, fe := range fields {
+			fe.Message = i18n.Message(lang, fe.Code, fe.Message, fe.Params...)
+			localized[i] = fe
+		}
+		return dto.ErrorResponse{
+			Code:   "validation_failed",
+			Error:  i18n.Message(lang, "validation_failed", "validation failed"),
+			Fields: localized,
+		}
+	}
+	var ue *usecase.Error
+	if errors.As(err, &ue) {
+		return dto.ErrorResponse{Code: ue.Code, Error: i18n.Message(lang, ue.Code, ue.Message, ue.Params...)}
+	}
+	code := kindCodes[usecase.ErrorKind(err)]
+	return dto.ErrorResponse{Code: code, Error: i18n.Message(lang, code, err.Error())}
+}
+
+// HandleUsecaseError answers with the status StatusForUsecaseError picks and
+// an ErrorResponse body, and counts the error in the usecase error metrics.
+func HandleUsecaseError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, op string, err error) {
+	metrics.RecordUsecaseError(r.Context(), usecase.ErrorKind(err))
+
+	status := StatusForUsecaseError(err)
+	body := ErrorBody(i18n.FromContext(r.Context()), err)
+	switch status {
+	case http.StatusBadRequest:
+		logger.Info("validation error", "op", op, "error", err)
+	case http.StatusConflict:
 		logger.Info("conflict error", "op", op, "error", err)
-		WriteTextError(w, http.StatusConflict, err.Error())
+	case http.StatusNotFound:
+		logger.Info("not found", "op", op, "error", err)
+	case http.StatusPreconditionFailed:
+		logger.Info("precondition failed", "op", op, "error", err)
 	default:
 		logger.Error("internal error", "op", op, "error", err)
-		WriteTextError(w, http.StatusInternalServerError, "internal error: "+err.Error())
+		body.Error += ": " + err.Error()
+	}
+	if err := WriteJSON(w, status, body); err != nil {
+		logger.Error("error writing error response", "op", op, "error", err)
 	}
 }
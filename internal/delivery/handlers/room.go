@@ -6,36 +6,28 @@ import (
 	"golangHotelProject/internal/delivery/handlers/dto"
 	"golangHotelProject/internal/delivery/handlers/helpers"
 	md "golangHotelProject/internal/model"
-	"golangHotelProject/internal/usecase"
 	"net/http"
 	"strconv"
 )
 
-var roomUC *usecase.RoomUsecase
-
-func InitDependencies(uc *usecase.RoomUsecase) error {
-	if uc == nil {
-		return fmt.Errorf("nil usecase")
-	}
-	roomUC = uc
-	return nil
-}
-
 // @Summary create room
 // @Tags room
 // @Description create room
 // @ID createRoom
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "makes retries of this request safe"
 // @Param input body md.Room true "new room data"
-// @Success 201 {object} dto.CreatingRoomResponse "Created"
+// @Success 201 {object} md.Room "Created"
+// @Header 201 {string} Location "URL of the created room"
+// @Header 201 {string} ETag "room version"
 // @Failure 400 {object} dto.ErrorResponse "Invalid JSON or validation error"
-// @Failure 409 {object} dto.ErrorResponse "Conflict (room already exists)"
+// @Failure 409 {object} dto.ErrorResponse "Conflict (room already exists or Idempotency-Key reused)"
 // @Failure 413 {object} dto.ErrorResponse "Request entity too large"
 // @Failure 415 {object} dto.ErrorResponse "Unsupported media type"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /Create [post]
-func Create(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "room.create")
 
 	if r.Method != http.MethodPost {
@@ -44,7 +36,7 @@ func Create(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		http.Error(w, "Method Not Allowed: ", http.StatusMethodNotAllowed)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -65,32 +57,92 @@ func Create(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Warn("invalid json",
 			"error", err)
-		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	} else {
 		log.Info("decoded room",
 			"room number", NewRoom.Number)
 	}
 
-	if err := roomUC.AddRoom(r.Context(), NewRoom); err != nil {
-		helpers.HandleUsecaseError(w, log, "add room", err)
+	created, err := h.rooms.AddRoom(r.Context(), NewRoom)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "add room", err)
 		return
 	}
 
 	log.Info("room added",
-		"room_number", NewRoom.Number)
+		"room_id", created.ID,
+		"room_number", created.Number)
 
-	response := map[string]int{"Number of added Room is": NewRoom.Number}
-	if err := helpers.WriteJSON(w, http.StatusCreated, response); err != nil {
+	w.Header().Set("Location", fmt.Sprintf("/ReadRoomByID?id=%d", created.ID))
+	helpers.SetETag(w, created.Version)
+	if err := helpers.WriteJSON(w, http.StatusCreated, created); err != nil {
 		log.Error("JSON encode error",
 			"error", err,
-			"room number", NewRoom.Number)
-		http.Error(w, "JSON encoding error: "+err.Error(), http.StatusInternalServerError)
+			"room_id", created.ID)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent",
 		"status", http.StatusCreated,
-		"room_number", NewRoom.Number)
+		"room_id", created.ID)
+}
+
+// @Summary read room
+// @Tags room
+// @Description get a single room by id; the ETag header carries its version
+// @ID readRoomByID
+// @Produce json
+// @Param id query int true "room id"
+// @Success 200 {object} md.Room "room"
+// @Header 200 {string} ETag "room version"
+// @Failure 400 {object} dto.ErrorResponse "Invalid id"
+// @Failure 404 {object} dto.ErrorResponse "Room not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /ReadRoomByID [get]
+func (h *Handler) ReadRoomByID(w http.ResponseWriter, r *http.Request) {
+	log := helpers.ReqLogger(r, "room.readByID")
+
+	if r.Method != http.MethodGet {
+		log.Warn(
+			"method not allowed",
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		log.Warn("missing id")
+		helpers.WriteError(w, r, http.StatusBadRequest, "missing_id")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		log.Warn("invalid id", "id", idStr)
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	log.Info("reading room", "room_id", id)
+
+	room, err := h.rooms.GetRoom(r.Context(), id)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "read room", err)
+		return
+	}
+
+	log.Info("room retrieved", "room_id", id, "version", room.Version)
+
+	helpers.SetETag(w, room.Version)
+	if err := helpers.WriteJSON(w, http.StatusOK, room); err != nil {
+		log.Error("JSON encode error", "error", err, "room_id", id)
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
+		return
+	}
+	log.Info("response sent", "status", http.StatusOK, "room_id", id)
 }
 
 // @Summary patch room
@@ -100,13 +152,18 @@ func Create(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id query int true "room id"
+// @Param If-Match header string true "ETag of the room version being patched"
 // @Param input body dto.RoomPatch true "patch data"
 // @Success 200 {object} dto.RoomPatchResponse "rooms updated"
+// @Header 200 {string} ETag "new room version"
 // @Failure 400 {object} dto.ErrorResponse "Invalid JSON or validation error"
+// @Failure 404 {object} dto.ErrorResponse "Room not found"
 // @Failure 409 {object} dto.ErrorResponse "Conflict"
+// @Failure 412 {object} dto.ErrorResponse "Room version has moved"
+// @Failure 428 {object} dto.ErrorResponse "If-Match header missing"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /Patch [patch]
-func Patch(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "room.patch")
 
 	if r.Method != http.MethodPatch {
@@ -115,7 +172,7 @@ func Patch(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -129,13 +186,19 @@ func Patch(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
 		log.Warn("missing id")
-		helpers.WriteTextError(w, http.StatusBadRequest, "missing id")
+		helpers.WriteError(w, r, http.StatusBadRequest, "missing_id")
 		return
 	}
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
 		log.Warn("invalid id", "id", idStr)
-		helpers.WriteTextError(w, http.StatusBadRequest, "invalid id")
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	version, err := helpers.IfMatchVersion(r)
+	if err != nil {
+		helpers.HandleIfMatchError(w, r, log, err)
 		return
 	}
 
@@ -147,23 +210,25 @@ func Patch(w http.ResponseWriter, r *http.Request) {
 	err = dec.Decode(&patch)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
-	log.Info("patching room", "room_id", id)
+	log.Info("patching room", "room_id", id, "version", version)
 
-	if err = roomUC.PatchRoom(r.Context(), id, patch); err != nil {
-		helpers.HandleUsecaseError(w, log, "patch room", err)
+	newVersion, err := h.rooms.PatchRoom(r.Context(), id, version, patch)
+	if err != nil {
+		helpers.HandleUsecaseError(w, r, log, "patch room", err)
 		return
 	}
 
-	log.Info("room patched", "room_id", id)
+	log.Info("room patched", "room_id", id, "version", newVersion)
 
+	helpers.SetETag(w, newVersion)
 	response := map[string]string{"status": "rooms updated"}
 	if err := helpers.WriteJSON(w, http.StatusOK, response); err != nil {
 		log.Error("JSON encode error", "error", err, "room_id", id)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "room_id", id)
@@ -175,13 +240,17 @@ func Patch(w http.ResponseWriter, r *http.Request) {
 // @ID removeRoom
 // @Accept json
 // @Produce json
+// @Param If-Match header string true "ETag of the room version being removed"
 // @Param input body dto.RemoveRoomRequest true "room id to remove"
 // @Success 200 {string} string "Removed Room id: {id}"
 // @Failure 400 {object} dto.ErrorResponse "Invalid JSON or validation error"
+// @Failure 404 {object} dto.ErrorResponse "Room not found"
 // @Failure 409 {object} dto.ErrorResponse "Conflict"
+// @Failure 412 {object} dto.ErrorResponse "Room version has moved"
+// @Failure 428 {object} dto.ErrorResponse "If-Match header missing"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /RemoveRoom [delete]
-func RemoveRoom(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RemoveRoom(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "room.remove")
 
 	if r.Method != http.MethodDelete {
@@ -190,7 +259,7 @@ func RemoveRoom(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -201,19 +270,25 @@ func RemoveRoom(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	version, err := helpers.IfMatchVersion(r)
+	if err != nil {
+		helpers.HandleIfMatchError(w, r, log, err)
+		return
+	}
+
 	var romovingRoomID int
 
-	err := json.NewDecoder(r.Body).Decode(&romovingRoomID)
+	err = json.NewDecoder(r.Body).Decode(&romovingRoomID)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "JSON encoding error"+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
-	log.Info("removing room", "room_id", romovingRoomID)
+	log.Info("removing room", "room_id", romovingRoomID, "version", version)
 
-	if err = roomUC.RemoveRoom(r.Context(), romovingRoomID); err != nil {
-		helpers.HandleUsecaseError(w, log, "remove room", err)
+	if err = h.rooms.RemoveRoom(r.Context(), romovingRoomID, version); err != nil {
+		helpers.HandleUsecaseError(w, r, log, "remove room", err)
 		return
 	}
 
@@ -222,7 +297,7 @@ func RemoveRoom(w http.ResponseWriter, r *http.Request) {
 	removedRoom := fmt.Sprintf("Removed Room id: %d", romovingRoomID)
 	if err := helpers.WriteJSON(w, http.StatusOK, removedRoom); err != nil {
 		log.Error("JSON encode error", "error", err, "room_id", romovingRoomID)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "room_id", romovingRoomID)
@@ -240,7 +315,7 @@ func RemoveRoom(w http.ResponseWriter, r *http.Request) {
 // @Failure 409 {object} dto.ErrorResponse "Conflict"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /GetFilteredRooms [post]
-func GetFilteredRooms(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetFilteredRooms(w http.ResponseWriter, r *http.Request) {
 	log := helpers.ReqLogger(r, "room.filter")
 
 	if r.Method != http.MethodPost {
@@ -249,7 +324,7 @@ func GetFilteredRooms(w http.ResponseWriter, r *http.Request) {
 			"method", r.Method,
 			"path", r.URL.Path,
 		)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		helpers.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed")
 		return
 	}
 
@@ -264,7 +339,7 @@ func GetFilteredRooms(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&filter)
 	if err != nil {
 		log.Warn("invalid json", "error", err)
-		helpers.WriteTextError(w, http.StatusBadRequest, "Invalid JSON format: "+err.Error())
+		helpers.WriteError(w, r, http.StatusBadRequest, "invalid_json", err.Error())
 		return
 	}
 
@@ -272,33 +347,33 @@ func GetFilteredRooms(w http.ResponseWriter, r *http.Request) {
 
 	if len(filter) == 0 {
 		log.Info("getting all rooms")
-		rooms, err := roomUC.GetList(r.Context())
+		rooms, err := h.rooms.GetList(r.Context())
 
 		if err != nil {
-			helpers.HandleUsecaseError(w, log, "get all rooms", err)
+			helpers.HandleUsecaseError(w, r, log, "get all rooms", err)
 			return
 		}
 
 		log.Info("rooms retrieved", "count", len(rooms))
 		if err := helpers.WriteJSON(w, http.StatusOK, rooms); err != nil {
 			log.Error("JSON encode error", "error", err)
-			helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error")
+			helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 			return
 		}
 		log.Info("response sent", "status", http.StatusOK, "count", len(rooms))
 		return
 	}
 
-	responses, err := roomUC.GetFilteredRooms(r.Context(), filter)
+	responses, err := h.rooms.GetFilteredRooms(r.Context(), filter)
 	if err != nil {
-		helpers.HandleUsecaseError(w, log, "get filtered rooms", err)
+		helpers.HandleUsecaseError(w, r, log, "get filtered rooms", err)
 		return
 	}
 
 	log.Info("filtered rooms retrieved", "filter", filter, "count", len(responses))
 	if err := helpers.WriteJSON(w, http.StatusOK, responses); err != nil {
 		log.Error("JSON encode error", "error", err, "filter", filter)
-		helpers.WriteTextError(w, http.StatusInternalServerError, "JSON encoding error: "+err.Error())
+		helpers.WriteError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 	log.Info("response sent", "status", http.StatusOK, "filter", filter, "count", len(responses))
@@ -0,0 +1,105 @@
+package i18n
+
+// catalogs map a language to its messages by code. Messages are fmt formats
+// and take the params of their code in the same order in every language.
+var catalogs = map[string]map[string]string{
+	English: {
+		// Envelope codes of errors without a more specific one.
+		"validation_failed":   "validation failed",
+		"invalid_request":     "invalid request",
+		"conflict":            "conflict",
+		"not_found":           "not found",
+		"precondition_failed": "the resource has changed since it was read",
+		"internal_error":      "internal error",
+
+		// Request errors the handlers answer before any usecase runs.
+		"method_not_allowed": "method not allowed",
+		"invalid_json":       "invalid JSON: %s",
+		"missing_id":         "missing id",
+		"invalid_id":         "id must be a positive integer",
+		"if_match_missing":   "If-Match header is required",
+		"if_match_invalid":   "If-Match must hold a single entity tag",
+
+		// Requests refused by middleware.
+		"rate_limited":             "rate limit exceeded, retry in %d s",
+		"idempotency_key_too_long": "Idempotency-Key must be at most %d characters",
+		"idempotency_key_reused":   "Idempotency-Key was already used with a different request payload",
+		"idempotency_in_progress":  "a request with this Idempotency-Key is still in progress",
+		"unreadable_body":          "cannot read the request body",
+
+		// Field rules.
+		"min":         "must be at least %d",
+		"one_of":      "must be one of: %s",
+		"required":    "is required",
+		"after_start": "must be after the start date",
+		"occupied":    "cannot be set while the room is occupied",
+
+		// Usecase errors.
+		"invalid_version":       "version must be more than 0",
+		"room_not_found":        "room not found",
+		"room_number_taken":     "room number already exists",
+		"room_occupied":         "room is occupied",
+		"room_booked":           "room is already booked for these dates",
+		"room_has_booking":      "the room already has an active booking",
+		"guest_has_booking":     "the guest already has an active booking",
+		"booking_not_found":     "booking not found",
+		"already_checked_in":    "booking is already checked in",
+		"not_checked_in":        "booking is not checked in",
+		"no_rooms":              "there are no rooms",
+		"no_bookings":           "there are no bookings",
+		"empty_batch":           "operations must not be empty",
+		"batch_too_large":       "at most %d operations per batch",
+		"batch_aborted":         "rolled back because operation %d failed",
+		"unsupported_operation": "unsupported operation %q on resource %q",
+		"data_required":         "data is required",
+		"invalid_data":          "invalid data: %s",
+		"unknown_filter_column": "unknown filter column %q",
+	},
+	Russian: {
+		"validation_failed":   "ошибка валидации",
+		"invalid_request":     "некорректный запрос",
+		"conflict":            "конфликт",
+		"not_found":           "не найдено",
+		"precondition_failed": "ресурс изменился после чтения",
+		"internal_error":      "внутренняя ошибка",
+
+		"method_not_allowed": "метод не поддерживается",
+		"invalid_json":       "некорректный JSON: %s",
+		"missing_id":         "не указан id",
+		"invalid_id":         "id должен быть положительным целым числом",
+		"if_match_missing":   "требуется заголовок If-Match",
+		"if_match_invalid":   "If-Match должен содержать один тег сущности",
+
+		"rate_limited":             "превышен лимит запросов, повторите через %d с",
+		"idempotency_key_too_long": "Idempotency-Key должен быть не длиннее %d символов",
+		"idempotency_key_reused":   "Idempotency-Key уже использован с другим телом запроса",
+		"idempotency_in_progress":  "запрос с этим Idempotency-Key ещё выполняется",
+		"unreadable_body":          "не удалось прочитать тело запроса",
+
+		"min":         "должно быть не меньше %d",
+		"one_of":      "должно быть одним из: %s",
+		"required":    "обязательное поле",
+		"after_start": "должна быть позже даты заезда",
+		"occupied":    "нельзя установить, пока номер занят",
+
+		"invalid_version":       "версия должна быть больше 0",
+		"room_not_found":        "номер не найден",
+		"room_number_taken":     "номер с таким номером уже существует",
+		"room_occupied":         "номер занят",
+		"room_booked":           "номер уже забронирован на эти даты",
+		"room_has_booking":      "у номера уже есть активное бронирование",
+		"guest_has_booking":     "у гостя уже есть активное бронирование",
+		"booking_not_found":     "бронирование не найдено",
+		"already_checked_in":    "гость уже заселён",
+		"not_checked_in":        "гость ещё не заселён",
+		"no_rooms":              "номеров нет",
+		"no_bookings":           "бронирований нет",
+		"empty_batch":           "список операций пуст",
+		"batch_too_large":       "не больше %d операций в пакете",
+		"batch_aborted":         "отменено, потому что операция %d завершилась ошибкой",
+		"unsupported_operation": "операция %q не поддерживается для ресурса %q",
+		"data_required":         "поле data обязательно",
+		"invalid_data":          "некорректные данные: %s",
+		"unknown_filter_column": "неизвестное поле фильтра %q",
+	},
+}
@@ -0,0 +1,97 @@
+// Package i18n holds the catalogs of API messages and picks the language a
+// request is answered in from its Accept-Language header.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+)
+
+// Languages are the languages with a catalog, English first.
+var Languages = []string{English, Russian}
+
+// Supported reports whether lang has a catalog.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Message returns the message for code in lang with params filled in. A code
+// missing from the lang catalog falls back to English, and one no catalog
+// knows to fallback, so that new codes degrade to their English text.
+func Message(lang, code, fallback string, params ...any) string {
+	format, ok := catalogs[lang][code]
+	if !ok {
+		format, ok = catalogs[English][code]
+	}
+	if !ok {
+		return fallback
+	}
+	if len(params) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, params...)
+}
+
+// Negotiate picks the supported language the Accept-Language header prefers,
+// e.g. "ru-RU,ru;q=0.9,en;q=0.8". Region subtags are ignored, "*" stands for
+// fallback and q=0 rules a language out. A header that names no supported
+// language gets fallback.
+func Negotiate(header, fallback string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(name, "q") {
+				v, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "*" {
+			primary = fallback
+		}
+		if q <= 0 || !Supported(primary) {
+			continue
+		}
+		choices = append(choices, choice{primary, q})
+	}
+	if len(choices) == 0 {
+		return fallback
+	}
+	// Equal weights keep the order the client listed them in.
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
+
+type ctxKey struct{}
+
+// WithLanguage returns a copy of ctx that carries lang.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// FromContext returns the language stored in ctx, or English.
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(ctxKey{}).(string); ok {
+		return lang
+	}
+	return English
+}
@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		header, want string
+	}{
+		{"", English},
+		{"ru", Russian},
+		{"ru-RU,ru;q=0.9,en;q=0.8", Russian},
+		{"EN-gb", English},
+		{"de,ru;q=0.5", Russian},
+		{"en;q=0.4,ru;q=0.6", Russian},
+		{"ru;q=0.5,en;q=0.5", Russian},
+		{"ru;q=0,en;q=0.1", English},
+		{"de, fr", English},
+		{"*", English},
+		{"ru;q=bogus", English},
+	} {
+		assert.Equal(t, tc.want, Negotiate(tc.header, English), "Accept-Language: %q", tc.header)
+	}
+	assert.Equal(t, Russian, Negotiate("de", Russian), "fallback for unsupported languages")
+	assert.Equal(t, Russian, Negotiate("*", Russian))
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "must be at least 3", Message(English, "min", "", 3))
+	assert.Equal(t, "должно быть не меньше 3", Message(Russian, "min", "", 3))
+	assert.Equal(t, "room not found", Message("de", "room_not_found", ""), "unknown languages read English")
+	assert.Equal(t, "as it was", Message(Russian, "no_such_code", "as it was"))
+}
+
+func TestCatalogsHaveTheSameCodes(t *testing.T) {
+	keys := func(m map[string]string) []string {
+		var out []string
+		for k := range m {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return out
+	}
+	for _, lang := range Languages {
+		assert.Equal(t, keys(catalogs[English]), keys(catalogs[lang]), lang)
+	}
+}
+
+func TestLanguageInContext(t *testing.T) {
+	assert.Equal(t, English, FromContext(context.Background()))
+	assert.Equal(t, Russian, FromContext(WithLanguage(context.Background(), Russian)))
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"sync"
+)
+
+// ComponentKey is the attribute the per-component levels are matched
+// against; the usecases and the migrator tag their loggers with it.
+const ComponentKey = "component"
+
+// Levels holds the log levels and can be changed while the service runs:
+// one base level and overrides for single components.
+type Levels struct {
+	base slog.LevelVar
+
+	mu         sync.RWMutex
+	components map[string]slog.Level
+}
+
+func NewLevels(base slog.Level) *Levels {
+	l := &Levels{components: make(map[string]slog.Level)}
+	l.base.Set(base)
+	return l
+}
+
+func (l *Levels) Base() slog.Level { return l.base.Level() }
+
+func (l *Levels) SetBase(level slog.Level) { l.base.Set(level) }
+
+// SetComponent makes component log at level regardless of the base level.
+func (l *Levels) SetComponent(component string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components[component] = level
+}
+
+// ResetComponent makes component follow the base level again.
+func (l *Levels) ResetComponent(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.components, component)
+}
+
+// Components returns a copy of the overrides.
+func (l *Levels) Components() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return maps.Clone(l.components)
+}
+
+// Level is the level component logs at.
+func (l *Levels) Level(component string) slog.Level {
+	if component != "" {
+		l.mu.RLock()
+		level, ok := l.components[component]
+		l.mu.RUnlock()
+		if ok {
+			return level
+		}
+	}
+	return l.base.Level()
+}
+
+// minLevel lets every record through the wrapped handler, so that
+// levelHandler alone decides.
+const minLevel = slog.Level(math.MinInt)
+
+// levelHandler filters records by the level of the component its logger was
+// tagged with through With.
+type levelHandler struct {
+	slog.Handler
+	levels    *Levels
+	component string
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.levels.Level(h.component) && h.Handler.Enabled(ctx, level)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			component = a.Value.String()
+		}
+	}
+	return levelHandler{Handler: h.Handler.WithAttrs(attrs), levels: h.levels, component: component}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithGroup(name), levels: h.levels, component: h.component}
+}
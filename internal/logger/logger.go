@@ -1,31 +1,89 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
+	"strings"
 )
 
-func InitLogger(level string) {
-	var logLevel slog.Level
+type Options struct {
+	Level string
+	// Format is json or text.
+	Format string
+	// Components maps component names to the levels they log at instead of
+	// Level.
+	Components map[string]string
+	// File, when set, receives the log lines as well. It is rotated at
+	// FileMaxSizeMB, keeping FileMaxBackups old files.
+	File           string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+	// Redact, when set, hides personal data before anything is written.
+	Redact *RedactOptions
+}
 
-	switch level {
-	case "DEBUG":
-		logLevel = slog.LevelDebug
-	case "INFO":
-		logLevel = slog.LevelInfo
-	case "WARN":
-		logLevel = slog.LevelWarn
-	case "ERROR":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+// Setup makes the process logger write to stdout, and to the log file if one
+// is set, and installs it as slog.Default(). It returns the levels, which can
+// be changed at runtime, and a func that closes the log file.
+func Setup(opts Options, stdout io.Writer) (*Levels, func() error, error) {
+	base, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	levels := NewLevels(base)
+	for component, s := range opts.Components {
+		level, err := ParseLevel(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("component %s: %w", component, err)
+		}
+		levels.SetComponent(component, level)
 	}
 
-	opts := &slog.HandlerOptions{Level: logLevel}
+	out, closeFile := stdout, func() error { return nil }
+	if opts.File != "" {
+		file, err := OpenRotatingFile(opts.File, int64(opts.FileMaxSizeMB)<<20, opts.FileMaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closeFile = io.MultiWriter(stdout, file), file.Close
+	}
 
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	handlerOpts := &slog.HandlerOptions{Level: minLevel}
+	var handler slog.Handler
+	switch opts.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		_ = closeFile()
+		return nil, nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	if opts.Redact != nil {
+		redacting, err := NewRedactHandler(handler, *opts.Redact)
+		if err != nil {
+			_ = closeFile()
+			return nil, nil, err
+		}
+		handler = redacting
+	}
 
-	logger := slog.New(handler)
+	slog.SetDefault(slog.New(TraceHandler{levelHandler{Handler: handler, levels: levels}}))
+	return levels, closeFile, nil
+}
 
-	slog.SetDefault(logger)
+// ParseLevel accepts DEBUG, INFO, WARN and ERROR in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return slog.LevelDebug, nil
+	case "INFO":
+		return slog.LevelInfo, nil
+	case "WARN":
+		return slog.LevelWarn, nil
+	case "ERROR":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q: want DEBUG, INFO, WARN or ERROR", s)
 }
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels_ComponentOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	levels := NewLevels(slog.LevelWarn)
+	log := slog.New(levelHandler{Handler: slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: minLevel}), levels: levels})
+	rooms := log.With(ComponentKey, "RoomUsecase", "op", "AddRoom")
+
+	log.Info("base info")
+	rooms.Info("rooms info")
+	levels.SetComponent("RoomUsecase", slog.LevelDebug)
+	rooms.Debug("rooms debug")
+	log.Debug("base debug")
+	levels.ResetComponent("RoomUsecase")
+	levels.SetBase(slog.LevelInfo)
+	rooms.Debug("rooms debug after reset")
+	log.Info("base info after change")
+
+	out := buf.String()
+	assert.NotContains(t, out, "base info\n")
+	assert.NotContains(t, out, "rooms info")
+	assert.Contains(t, out, "rooms debug")
+	assert.NotContains(t, out, "base debug")
+	assert.NotContains(t, out, "rooms debug after reset")
+	assert.Contains(t, out, "base info after change")
+}
+
+func TestSetup_TextFormatAndFile(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	path := filepath.Join(t.TempDir(), "logs", "hotel.log")
+
+	var stdout bytes.Buffer
+	levels, closeFile, err := Setup(Options{
+		Level:          "info",
+		Format:         "text",
+		Components:     map[string]string{"Migrator": "ERROR"},
+		File:           path,
+		FileMaxSizeMB:  1,
+		FileMaxBackups: 1,
+	}, &stdout)
+	require.NoError(t, err)
+
+	slog.Info("hello", "room", 101)
+	slog.Default().With(ComponentKey, "Migrator").Info("hidden")
+	require.NoError(t, closeFile())
+
+	assert.Equal(t, slog.LevelError, levels.Level("Migrator"))
+	assert.Contains(t, stdout.String(), "level=INFO msg=hello room=101")
+	assert.NotContains(t, stdout.String(), "hidden")
+	file, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, stdout.String(), string(file))
+}
+
+func TestSetup_RejectsUnknownLevel(t *testing.T) {
+	_, _, err := Setup(Options{Level: "LOUD"}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "LOUD")
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hotel.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	read := func(name string) string {
+		b, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, "fourth\n", read(path))
+	assert.Equal(t, "third\n", read(path+".1"))
+	assert.Equal(t, "second\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3", "only maxBackups old files are kept")
+
+	_, err = f.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.False(t, strings.Contains(read(path), "late"))
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// BuiltinPatterns are the patterns RedactOptions.Patterns can name instead of
+// spelling out a regular expression.
+var BuiltinPatterns = map[string]string{
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	// International numbers with a leading + and Russian ones starting
+	// with 8, with the usual separators.
+	"phone": `\+\d[\d\s().-]{7,}\d|\b8[\s-]?\(?\d{3}\)?[\s-]?\d{3}(?:[\s-]?\d{2}){2}\b`,
+	// Russian passports (4510 123456) and international ones (AB1234567).
+	"document": `\b\d{2}\s?\d{2}\s?\d{6}\b|\b[A-Z]{1,2}\d{6,9}\b`,
+}
+
+// DefaultRedactKeys are attributes that hold personal data or secrets
+// whatever their value looks like.
+var DefaultRedactKeys = []string{
+	"first_name", "last_name", "full_name", "guest_name",
+	"email", "phone", "phone_number", "document", "document_number", "passport",
+	"password", "authorization", "token", "api_key", "secret",
+}
+
+type RedactOptions struct {
+	// Keys are attribute and map keys whose values are always hidden. They
+	// match case-insensitively, ignoring _ and -, so GuestName matches
+	// guest_name.
+	Keys []string
+	// Patterns are hidden wherever they occur in the message and in string
+	// values: the name of a builtin pattern or a regular expression.
+	Patterns []string
+	// Hash replaces values with a salted hash instead of [REDACTED], so
+	// that lines about the same guest can still be matched up. It needs a
+	// secret HashSalt: phone and document numbers are few enough that an
+	// unsalted hash can be reversed by trying them all.
+	Hash     bool
+	HashSalt string
+}
+
+// RedactHandler hides personal data before next sees the record: values of
+// sensitive keys, also inside groups, maps and structs, and pattern matches
+// in the message and string values.
+type RedactHandler struct {
+	slog.Handler
+	r *redactor
+}
+
+func NewRedactHandler(next slog.Handler, opts RedactOptions) (*RedactHandler, error) {
+	if opts.Hash && opts.HashSalt == "" {
+		return nil, errors.New("redact: hashing needs a salt")
+	}
+	r := &redactor{keys: make(map[string]bool, len(opts.Keys)), hash: opts.Hash, salt: []byte(opts.HashSalt)}
+	for _, k := range opts.Keys {
+		r.keys[normalizeKey(k)] = true
+	}
+	for _, p := range opts.Patterns {
+		expr := p
+		if builtin, ok := BuiltinPatterns[p]; ok {
+			expr = builtin
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return &RedactHandler{Handler: next, r: r}, nil
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.r.scrub(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.r.attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.r.attr(a)
+	}
+	return &RedactHandler{Handler: h.Handler.WithAttrs(redacted), r: h.r}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{Handler: h.Handler.WithGroup(name), r: h.r}
+}
+
+type redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	hash     bool
+	salt     []byte
+}
+
+func normalizeKey(k string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(k))
+}
+
+func (r *redactor) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if r.keys[normalizeKey(a.Key)] {
+		return slog.String(a.Key, r.replace(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(r.scrub(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = r.attr(ga)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		a.Value = r.any(a.Value.Any())
+	}
+	return a
+}
+
+// any redacts arbitrary values through their JSON form, which is also what
+// the JSON handler prints. Values that don't marshal are left alone.
+func (r *redactor) any(v any) slog.Value {
+	if err, ok := v.(error); ok {
+		return slog.StringValue(r.scrub(err.Error()))
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return slog.AnyValue(v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return slog.AnyValue(v)
+	}
+
+	switch g := generic.(type) {
+	case map[string]any, []any:
+		return slog.AnyValue(r.walk(g))
+	case string:
+		return slog.StringValue(r.scrub(g))
+	}
+	return slog.AnyValue(v)
+}
+
+func (r *redactor) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if r.keys[normalizeKey(k)] {
+				v[k] = r.replace(fmt.Sprint(val))
+			} else {
+				v[k] = r.walk(val)
+			}
+		}
+		return v
+	case []any:
+		for i, val := range v {
+			v[i] = r.walk(val)
+		}
+		return v
+	case string:
+		return r.scrub(v)
+	}
+	return v
+}
+
+func (r *redactor) scrub(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, r.replace)
+	}
+	return s
+}
+
+func (r *redactor) replace(s string) string {
+	if !r.hash {
+		return Redacted
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(s))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
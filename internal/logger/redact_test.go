@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedactingLogger(t *testing.T, opts RedactOptions) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	h, err := NewRedactHandler(slog.NewJSONHandler(&buf, nil), opts)
+	require.NoError(t, err)
+	return slog.New(h), &buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line), buf.String())
+	return line
+}
+
+var defaultRedact = RedactOptions{Keys: DefaultRedactKeys, Patterns: []string{"email", "phone", "document"}}
+
+func TestRedact_SensitiveKeys(t *testing.T) {
+	log, buf := newRedactingLogger(t, defaultRedact)
+
+	log.With("guest_name", "Ivan Petrov").Info("booking created",
+		"Email", "ivan@example.com",
+		"room_id", 101,
+		slog.Group("guest", "passport", "4510 123456", "nights", 3),
+	)
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, Redacted, line["guest_name"], "attributes added through With are redacted too")
+	assert.Equal(t, Redacted, line["Email"], "keys match case-insensitively")
+	assert.EqualValues(t, 101, line["room_id"])
+	assert.Equal(t, map[string]any{"passport": Redacted, "nights": float64(3)}, line["guest"])
+	assert.NotContains(t, buf.String(), "Petrov")
+}
+
+func TestRedact_Patterns(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"email", "contact ivan.petrov+hotel@mail.example.com now", "contact [REDACTED] now"},
+		{"international phone", "call +7 (916) 123-45-67", "call [REDACTED]"},
+		{"russian phone", "call 8 916 123 45 67", "call [REDACTED]"},
+		{"russian passport", "passport 4510 123456 checked", "passport [REDACTED] checked"},
+		{"international passport", "passport AB1234567", "passport [REDACTED]"},
+		{"dates stay", "from 2026-10-19T03:50:13Z to 2026-10-21", "from 2026-10-19T03:50:13Z to 2026-10-21"},
+		{"ids stay", "booking 42 room 101 version 3", "booking 42 room 101 version 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, buf := newRedactingLogger(t, defaultRedact)
+			log.Info(tt.in, "detail", tt.in)
+
+			line := decodeLine(t, buf)
+			assert.Equal(t, tt.want, line["msg"])
+			assert.Equal(t, tt.want, line["detail"])
+		})
+	}
+}
+
+func TestRedact_MapsStructsAndErrors(t *testing.T) {
+	log, buf := newRedactingLogger(t, defaultRedact)
+	type guest struct {
+		FirstName string `json:"first_name"`
+		Note      string `json:"note"`
+	}
+
+	log.Info("getting filtered bookings",
+		"filter", map[string]any{"room_id": 101, "guest_email": "ivan@example.com", "phone": "+79161234567"},
+		"guest", guest{FirstName: "Ivan", Note: "late check-in"},
+		"error", errors.New("no booking for ivan@example.com"),
+	)
+
+	line := decodeLine(t, buf)
+	assert.Equal(t, map[string]any{"room_id": float64(101), "guest_email": Redacted, "phone": Redacted}, line["filter"])
+	assert.Equal(t, map[string]any{"first_name": Redacted, "note": "late check-in"}, line["guest"])
+	assert.Equal(t, "no booking for [REDACTED]", line["error"])
+}
+
+func TestRedact_HashNeedsSalt(t *testing.T) {
+	_, err := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactOptions{Keys: []string{"phone"}, Hash: true})
+	assert.Error(t, err)
+}
+
+func TestRedact_HashKeepsValuesComparable(t *testing.T) {
+	log, buf := newRedactingLogger(t, RedactOptions{Keys: []string{"email"}, Hash: true, HashSalt: "pepper"})
+
+	log.Info("first", "email", "ivan@example.com")
+	log.Info("second", "email", "ivan@example.com")
+	log.Info("third", "email", "anna@example.com")
+
+	var hashes []string
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		hashes = append(hashes, line["email"].(string))
+	}
+	assert.Regexp(t, "^sha256:[0-9a-f]{16}$", hashes[0])
+	assert.Equal(t, hashes[0], hashes[1])
+	assert.NotEqual(t, hashes[0], hashes[2])
+	assert.NotContains(t, buf.String(), "example.com")
+}
+
+func TestRedact_CustomPattern(t *testing.T) {
+	log, buf := newRedactingLogger(t, RedactOptions{Patterns: []string{`card-\d{4}`}})
+	log.Info("paid with card-1234")
+	assert.Equal(t, "paid with [REDACTED]", decodeLine(t, buf)["msg"])
+
+	_, err := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactOptions{Patterns: []string{"("}})
+	assert.ErrorContains(t, err, "redact pattern")
+}
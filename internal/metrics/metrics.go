@@ -0,0 +1,150 @@
+// Package metrics exposes the service's Prometheus metrics. Each App owns
+// its own registry, so instances in one process (and tests) never share
+// counters.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golangHotelProject/internal/middleware"
+	"golangHotelProject/internal/usecase"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "hotel"
+
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	inFlight      prometheus.Gauge
+	usecaseErrors *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by handler, method and status code.",
+		}, []string{"handler", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by handler and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		usecaseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "usecase_errors_total",
+			Help:      "Usecase errors answered by handler and kind (validation, conflict, not_found, precondition, internal).",
+		}, []string{"handler", "kind"}),
+	}
+	m.Registry.MustRegister(
+		m.requests, m.duration, m.inFlight, m.usecaseErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// RegisterDB exports the connection pool statistics of conn.
+func (m *Metrics) RegisterDB(conn *sql.DB, name string) {
+	m.Registry.MustRegister(collectors.NewDBStatsCollector(conn, name))
+}
+
+// RoomStatsSource is what the business gauges are read from on every scrape.
+type RoomStatsSource interface {
+	Stats(ctx context.Context) (usecase.RoomStats, error)
+}
+
+// RegisterRoomStats exports the room gauges. A scrape waits at most timeout
+// for the numbers and leaves the gauges out if they are not available.
+func (m *Metrics) RegisterRoomStats(src RoomStatsSource, timeout time.Duration, log *slog.Logger) {
+	m.Registry.MustRegister(&roomCollector{src: src, timeout: timeout, log: log})
+}
+
+// Instrument counts and times the requests next serves under the handler
+// label name, and lets helpers.HandleUsecaseError count usecase errors for
+// the same handler via RecordUsecaseError.
+func (m *Metrics) Instrument(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		rec := middleware.NewStatusRecorder(w)
+		record := func(kind string) { m.usecaseErrors.WithLabelValues(name, kind).Inc() }
+		ctx := context.WithValue(r.Context(), errorRecorderKey{}, record)
+
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		m.duration.WithLabelValues(name, r.Method).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(name, r.Method, strconv.Itoa(rec.Status)).Inc()
+	})
+}
+
+type errorRecorderKey struct{}
+
+// RecordUsecaseError counts a usecase error of the given kind against the
+// handler serving ctx. It does nothing outside an instrumented request.
+func RecordUsecaseError(ctx context.Context, kind string) {
+	if record, ok := ctx.Value(errorRecorderKey{}).(func(string)); ok {
+		record(kind)
+	}
+}
+
+type roomCollector struct {
+	src     RoomStatsSource
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+var (
+	roomsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rooms"),
+		"Rooms in the hotel.", nil, nil)
+	roomsOccupiedDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rooms_occupied"),
+		"Rooms with a guest checked in.", nil, nil)
+	roomsNeedCleaningDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rooms_need_cleaning"),
+		"Rooms waiting for housekeeping.", nil, nil)
+)
+
+func (c *roomCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- roomsDesc
+	ch <- roomsOccupiedDesc
+	ch <- roomsNeedCleaningDesc
+}
+
+func (c *roomCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.src.Stats(ctx)
+	if err != nil {
+		c.log.Warn("room stats unavailable for metrics", "error", err.Error())
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(roomsDesc, prometheus.GaugeValue, float64(stats.Total))
+	ch <- prometheus.MustNewConstMetric(roomsOccupiedDesc, prometheus.GaugeValue, float64(stats.Occupied))
+	ch <- prometheus.MustNewConstMetric(roomsNeedCleaningDesc, prometheus.GaugeValue, float64(stats.NeedCleaning))
+}
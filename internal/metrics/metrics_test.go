@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/usecase"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument_CountsRequestsAndUsecaseErrors(t *testing.T) {
+	m := New()
+	h := m.Instrument("room.create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			RecordUsecaseError(r.Context(), "conflict")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	for _, target := range []string{"/Create", "/Create", "/Create?fail=1"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, target, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("room.create", "POST", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("room.create", "POST", "409")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.usecaseErrors.WithLabelValues("room.create", "conflict")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.duration))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inFlight))
+}
+
+func TestRecordUsecaseError_OutsideInstrumentedRequest(t *testing.T) {
+	assert.NotPanics(t, func() { RecordUsecaseError(context.Background(), "internal") })
+}
+
+type stubStats struct {
+	stats usecase.RoomStats
+	err   error
+}
+
+func (s stubStats) Stats(context.Context) (usecase.RoomStats, error) { return s.stats, s.err }
+
+func TestRoomStatsGauges(t *testing.T) {
+	quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m := New()
+	m.RegisterRoomStats(stubStats{stats: usecase.RoomStats{Total: 10, Occupied: 4, NeedCleaning: 2}}, time.Second, quiet)
+
+	err := testutil.GatherAndCompare(m.Registry, strings.NewReader(`
+# HELP hotel_rooms Rooms in the hotel.
+# TYPE hotel_rooms gauge
+hotel_rooms 10
+# HELP hotel_rooms_need_cleaning Rooms waiting for housekeeping.
+# TYPE hotel_rooms_need_cleaning gauge
+hotel_rooms_need_cleaning 2
+# HELP hotel_rooms_occupied Rooms with a guest checked in.
+# TYPE hotel_rooms_occupied gauge
+hotel_rooms_occupied 4
+`), "hotel_rooms", "hotel_rooms_occupied", "hotel_rooms_need_cleaning")
+	require.NoError(t, err)
+
+	broken := New()
+	broken.RegisterRoomStats(stubStats{err: errors.New("database is down")}, time.Second, quiet)
+	n, err := testutil.GatherAndCount(broken.Registry, "hotel_rooms")
+	require.NoError(t, err, "a failing source must not fail the scrape")
+	assert.Zero(t, n)
+}
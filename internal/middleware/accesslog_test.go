@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog_OneLinePerRequest(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(RequestID(slog.New(slog.NewJSONHandler(&buf, nil))), AccessLog)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ReadRoomByID?id=7", nil)
+	req.Header.Set(RequestIDHeader, "req-7")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 1)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &line))
+	assert.Equal(t, "request completed", line["msg"])
+	assert.Equal(t, "req-7", line["request_id"])
+	assert.Equal(t, "/ReadRoomByID", line["path"])
+	assert.Equal(t, "id=7", line["query"])
+	assert.EqualValues(t, http.StatusTeapot, line["status"])
+	assert.EqualValues(t, len("short and stout"), line["bytes"])
+	assert.Contains(t, line, "duration_ms")
+}
+
+func TestAccessLog_ServerErrorsAtErrorLevel(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(RequestID(slog.New(slog.NewJSONHandler(&buf, nil))), AccessLog, Recover)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2, "the panic and the access line")
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(lines[1], &line))
+	assert.Equal(t, "ERROR", line["level"])
+	assert.EqualValues(t, http.StatusInternalServerError, line["status"])
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import "net/http"
+
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes ms into one middleware. The first one is the outermost:
+// it sees the request first and the response last.
+func Chain(ms ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(ms) - 1; i >= 0; i-- {
+			h = ms[i](h)
+		}
+		return h
+	}
+}
@@ -1,19 +1,161 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
 
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "3600")
+// CORSOptions configures CORS. Header names are matched case-insensitively.
+type CORSOptions struct {
+	// AllowedOrigins holds exact origins, patterns with one wildcard such as
+	// https://*.example.com, or * for any origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS answers preflight requests itself and adds the CORS headers to the
+// responses for allowed origins. Requests from other origins pass through
+// without CORS headers, so the browser blocks the response; their preflight
+// requests are refused with 403.
+func CORS(opts CORSOptions) Middleware {
+	c := newCORS(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if preflight {
+				c.preflight(w, r, origin)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			if origin != "" && c.originAllowed(origin) {
+				c.setOrigin(w, origin)
+				if c.exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type cors struct {
+	anyOrigin   bool
+	exact       map[string]bool
+	patterns    []originPattern
+	methods     map[string]bool
+	headers     map[string]bool
+	allowMethod string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// originPattern is an allowed origin with one wildcard, split around it.
+type originPattern struct {
+	prefix, suffix string
+}
+
+func newCORS(opts CORSOptions) *cors {
+	c := &cors{
+		exact:       make(map[string]bool),
+		methods:     make(map[string]bool),
+		headers:     make(map[string]bool),
+		allowMethod: strings.Join(opts.AllowedMethods, ", "),
+		exposed:     strings.Join(opts.ExposedHeaders, ", "),
+		credentials: opts.AllowCredentials,
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			c.patterns = append(c.patterns, originPattern{prefix: prefix, suffix: suffix})
+		default:
+			c.exact[o] = true
+		}
+	}
+	for _, m := range opts.AllowedMethods {
+		c.methods[strings.ToUpper(m)] = true
+	}
+	for _, h := range opts.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return c
+}
 
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.exact[origin] {
+		return true
+	}
+	for _, p := range c.patterns {
+		if len(origin) > len(p.prefix)+len(p.suffix) &&
+			strings.HasPrefix(origin, p.prefix) && strings.HasSuffix(origin, p.suffix) {
+			return true
 		}
+	}
+	return false
+}
+
+// setOrigin allows origin. With credentials the origin has to be echoed:
+// browsers reject * on credentialed requests.
+func (c *cors) setOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin && !c.credentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if origin == "" || !c.originAllowed(origin) || !c.methods[method] || !c.headersAllowed(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	c.setOrigin(w, origin)
+	h.Set("Access-Control-Allow-Methods", c.allowMethod)
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *cors) headersAllowed(r *http.Request) bool {
+	for _, name := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !c.headers[http.CanonicalHeaderKey(name)] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func corsHandler(opts CORSOptions) http.Handler {
+	return CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+}
+
+var testCORS = CORSOptions{
+	AllowedOrigins: []string{"http://localhost:3000", "https://*.example.com"},
+	AllowedMethods: []string{"GET", "POST", "PATCH"},
+	AllowedHeaders: []string{"Content-Type", "If-Match", "X-Request-ID"},
+	ExposedHeaders: []string{"ETag", "X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		want    int
+	}{
+		{"exact origin", "http://localhost:3000", "PATCH", "content-type, if-match", http.StatusNoContent},
+		{"wildcard origin", "https://app.example.com", "POST", "", http.StatusNoContent},
+		{"wildcard needs a subdomain", "https://.example.com", "POST", "", http.StatusForbidden},
+		{"unknown origin", "https://evil.test", "GET", "", http.StatusForbidden},
+		{"method not allowed", "http://localhost:3000", "DELETE", "", http.StatusForbidden},
+		{"header not allowed", "http://localhost:3000", "GET", "X-Debug", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/Create", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			corsHandler(testCORS).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+			if tt.want != http.StatusNoContent {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+			assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "GET, POST, PATCH", rec.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, tt.headers, rec.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       CORSOptions
+		origin     string
+		wantOrigin string
+		wantCreds  string
+	}{
+		{"allowed origin is echoed", testCORS, "https://app.example.com", "https://app.example.com", ""},
+		{"other origins get no CORS headers", testCORS, "https://evil.test", "", ""},
+		{"any origin", CORSOptions{AllowedOrigins: []string{"*"}}, "https://evil.test", "*", ""},
+		{
+			"credentials echo the origin",
+			CORSOptions{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			"https://app.example.com", "https://app.example.com", "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ReadRoomByID?id=1", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			corsHandler(tt.opts).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusTeapot, rec.Code, "the request reaches the handler")
+			assert.Equal(t, tt.wantOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantCreds, rec.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, []string{"Origin"}, rec.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestCORS_ExposesHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	rec := httptest.NewRecorder()
+	corsHandler(testCORS).ServeHTTP(rec, req)
+
+	assert.Equal(t, "ETag, X-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORS_PlainOptionsReachesTheHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/Create", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	rec := httptest.NewRecorder()
+	corsHandler(testCORS).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/logger"
+)
+
+// errorResponse has the shape of the handlers' dto.ErrorResponse, so that a
+// request refused by middleware reads the same as one refused by a handler.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError answers with code and its message in the language of the
+// request. The request ID is included when RequestID has set one.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, params ...any) {
+	body := errorResponse{
+		Code:      code,
+		Error:     i18n.Message(i18n.FromContext(r.Context()), code, code, params...),
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.FromContext(r.Context()).Error("error writing error response", "code", code, "error", err)
+	}
+}
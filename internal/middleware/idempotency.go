@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/logger"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// IdempotencyRecord is what is remembered about a request made under an
+// Idempotency-Key. Done is false while the first request is still running.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Done        bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// IdempotencyStore keeps idempotency records. Reserve must be atomic: of two
+// concurrent callers with the same key only one may get true.
+type IdempotencyStore interface {
+	Reserve(key, fingerprint string, ttl time.Duration) (IdempotencyRecord, bool)
+	Save(key string, rec IdempotencyRecord)
+	Release(key string)
+}
+
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]IdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string, ttl time.Duration) (IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		return rec, false
+	}
+
+	rec := IdempotencyRecord{Fingerprint: fingerprint, ExpiresAt: now.Add(ttl)}
+	s.records[key] = rec
+	return rec, true
+}
+
+func (s *MemoryIdempotencyStore) Save(key string, rec IdempotencyRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = rec
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+// Sweep drops expired records and reports how many were removed.
+func (s *MemoryIdempotencyStore) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RunJanitor sweeps the store every interval until ctx is cancelled.
+func (s *MemoryIdempotencyStore) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := s.Sweep(); n > 0 {
+				slog.Debug("idempotency records expired", "count", n)
+			}
+		}
+	}
+}
+
+// Idempotency makes POST handlers safe to retry. The first response for an
+// Idempotency-Key is stored for ttl and replayed for retries carrying the same
+// body in the same language; reusing the key with a different body or
+// language is a conflict. Keys are scoped by caller, the first of the client
+// keys it returns (see RateLimitKey), so that one client cannot fetch
+// another's response by guessing its key. Server errors are not stored so
+// that the client can retry them.
+func Idempotency(store IdempotencyStore, ttl time.Duration, caller func(*http.Request) []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := logger.FromContext(r.Context()).With(
+				"middleware", "idempotency",
+				"path", r.URL.Path,
+				"idempotency_key", key,
+			)
+
+			if len(key) > maxIdempotencyKeyLength {
+				log.Warn("idempotency key too long", "length", len(key))
+				writeError(w, r, http.StatusBadRequest, "idempotency_key_too_long", maxIdempotencyKeyLength)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+			if err != nil {
+				log.Warn("cannot read request body", "error", err)
+				writeError(w, r, http.StatusBadRequest, "unreadable_body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scoped := r.URL.Path + "|" + caller(r)[0] + "|" + key
+			fingerprint := requestFingerprint(r, body)
+
+			rec, reserved := store.Reserve(scoped, fingerprint, ttl)
+			if !reserved {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					log.Warn("idempotency key reused with different payload")
+					writeError(w, r, http.StatusConflict, "idempotency_key_reused")
+				case !rec.Done:
+					log.Info("request with idempotency key still in progress")
+					writeError(w, r, http.StatusConflict, "idempotency_in_progress")
+				default:
+					log.Info("replaying stored response", "status", rec.Status)
+					replay(w, log, rec)
+				}
+				return
+			}
+
+			// Headers set before the handler runs belong to outer middleware
+			// (request ID, CORS, rate limits, language) and are computed
+			// afresh for a retry, so only the handler's own are stored.
+			outer := make(map[string]bool, len(w.Header()))
+			for k := range w.Header() {
+				outer[k] = true
+			}
+
+			rw := &capturingWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(scoped)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= http.StatusInternalServerError {
+				log.Info("not storing server error response", "status", rw.status)
+				return
+			}
+
+			rec.Done = true
+			rec.Status = rw.status
+			rec.Header = make(http.Header)
+			for k, v := range rw.Header() {
+				if !outer[k] {
+					rec.Header[k] = slices.Clone(v)
+				}
+			}
+			rec.Body = rw.body.Bytes()
+			store.Save(scoped, rec)
+			completed = true
+		})
+	}
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	// The stored messages are in the language of the first request.
+	h.Write([]byte(r.Method + " " + r.URL.Path + " " + i18n.FromContext(r.Context()) + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, log *slog.Logger, rec IdempotencyRecord) {
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(rec.Status)
+	if _, err := w.Write(rec.Body); err != nil {
+		log.Error("error writing replayed response", "error", err)
+	}
+}
+
+// capturingWriter passes the response through while keeping a copy of it.
+type capturingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (c *capturingWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *capturingWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countingHandler(calls *int, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"n":1}`))
+	})
+}
+
+func postWithKey(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/CreateBooking", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusCreated))
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, postWithKey("k1", `{"room_id":1}`))
+
+	second := httptest.NewRecorder()
+	h.ServeHTTP(second, postWithKey("k1", `{"room_id":1}`))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+}
+
+func TestIdempotency_ReplayKeepsTheRetrysOwnOuterHeaders(t *testing.T) {
+	calls := 0
+	h := Chain(
+		CORS(testCORS),
+		RateLimit(NewMemoryRateLimitStore(), "booking", Rate{Requests: 5, Per: time.Minute}, RateLimitKey("ip", nil)),
+		Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil)),
+	)(countingHandler(&calls, http.StatusCreated))
+
+	post := func(origin string) *httptest.ResponseRecorder {
+		req := postWithKey("k1", `{"room_id":1}`)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	first := post("https://app.example.com")
+	second := post("http://localhost:3000")
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"), "the handler's headers are replayed")
+	assert.Equal(t, "4", first.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "3", second.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "http://localhost:3000", second.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, second.Header().Values("Vary"))
+}
+
+func TestIdempotency_DifferentPayloadIsConflict(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusCreated))
+
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("k1", `{"room_id":1}`))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, postWithKey("k1", `{"room_id":2}`))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"code":"idempotency_key_reused","error":"Idempotency-Key was already used with a different request payload"}`, rec.Body.String())
+}
+
+func TestIdempotency_KeysAreScopedByCaller(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusCreated))
+
+	for _, apiKey := range []string{"alice", "mallory"} {
+		req := postWithKey("k1", `{"room_id":1}`)
+		req.Header.Set(APIKeyHeader, apiKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Empty(t, rec.Header().Get(IdempotentReplayedHeader), apiKey)
+	}
+	assert.Equal(t, 2, calls, "another caller's key does not replay its response")
+}
+
+func TestIdempotency_RetryInAnotherLanguageIsConflict(t *testing.T) {
+	calls := 0
+	h := Chain(Language(i18n.English), Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil)))(countingHandler(&calls, http.StatusCreated))
+
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("k1", `{"room_id":1}`))
+
+	req := postWithKey("k1", `{"room_id":1}`)
+	req.Header.Set("Accept-Language", "ru")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestIdempotency_UnreadableBodyIsNotEchoed(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusCreated))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, postWithKey("k1", strings.Repeat("x", maxIdempotentRequestBytes+1)))
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"code":"unreadable_body","error":"cannot read the request body"}`, rec.Body.String())
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusInternalServerError))
+
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("k1", `{}`))
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("k1", `{}`))
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_WithoutKeyPassesThrough(t *testing.T) {
+	calls := 0
+	h := Idempotency(NewMemoryIdempotencyStore(), time.Hour, RateLimitKey("api_key", nil))(countingHandler(&calls, http.StatusCreated))
+
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("", `{}`))
+	h.ServeHTTP(httptest.NewRecorder(), postWithKey("", `{}`))
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestMemoryIdempotencyStore_ExpiredRecordsAreSwept(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, ok := store.Reserve("k1", "fp", time.Minute)
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 1, store.Sweep())
+
+	_, ok = store.Reserve("k1", "other", time.Minute)
+	assert.True(t, ok)
+}
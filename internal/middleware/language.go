@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"golangHotelProject/internal/i18n"
+)
+
+// Language picks the language of the response messages from Accept-Language,
+// falling back to fallback, stores it in the request context (see
+// i18n.FromContext) and announces it in Content-Language.
+func Language(fallback string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := i18n.Negotiate(r.Header.Get("Accept-Language"), fallback)
+			w.Header().Set("Content-Language", lang)
+			w.Header().Add("Vary", "Accept-Language")
+			next.ServeHTTP(w, r.WithContext(i18n.WithLanguage(r.Context(), lang)))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golangHotelProject/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	var got string
+	h := Language(i18n.Russian)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = i18n.FromContext(r.Context())
+	}))
+
+	for header, want := range map[string]string{
+		"":                 i18n.Russian,
+		"en-US,en;q=0.9":   i18n.English,
+		"de,ru;q=0.3":      i18n.Russian,
+		"fr;q=0.9,en;q=.5": i18n.English,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Accept-Language", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, want, got, "Accept-Language: %q", header)
+		assert.Equal(t, want, rec.Header().Get("Content-Language"))
+		assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+	}
+}
@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golangHotelProject/internal/logger"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	APIKeyHeader             = "X-API-Key"
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// Rate is a token bucket that holds up to Burst tokens and is refilled with
+// Requests tokens every Per. A zero Burst means Requests.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func (r Rate) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// tokensPerSecond is the refill rate of the bucket.
+func (r Rate) tokensPerSecond() float64 {
+	return float64(r.Requests) / r.Per.Seconds()
+}
+
+// RateDecision is the outcome of taking a token. Reset is how long the bucket
+// takes to fill up again, RetryAfter how long a refused client has to wait
+// for the next token.
+type RateDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. Take spends a token from every
+// bucket of keys only if each of them has one, and reports the decision of
+// the tightest; a refused request spends nothing. Take must be atomic so that
+// concurrent requests cannot spend the same token; a store shared between
+// instances makes the limits hold for the whole deployment.
+type RateLimitStore interface {
+	Take(keys []string, rate Rate) RateDecision
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will be full again; from then on it is no
+	// different from a new one.
+	full time.Time
+}
+
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(keys []string, rate Rate) RateDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := rate.capacity()
+	perSecond := rate.tokensPerSecond()
+
+	buckets := make([]*tokenBucket, len(keys))
+	allowed := true
+	for i, key := range keys {
+		b, ok := s.buckets[key]
+		if !ok {
+			b = &tokenBucket{tokens: capacity, last: now}
+			s.buckets[key] = b
+		}
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+		b.last = now
+		buckets[i] = b
+		allowed = allowed && b.tokens >= 1
+	}
+
+	var tightest RateDecision
+	for i, b := range buckets {
+		d := RateDecision{Limit: int(capacity), Allowed: allowed}
+		if allowed {
+			b.tokens--
+		} else if b.tokens < 1 {
+			d.RetryAfter = seconds((1 - b.tokens) / perSecond)
+		}
+		d.Remaining = int(b.tokens)
+		d.Reset = seconds((capacity - b.tokens) / perSecond)
+		b.full = now.Add(d.Reset)
+		if i == 0 || tighter(d, tightest) {
+			tightest = d
+		}
+	}
+	return tightest
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Sweep drops the buckets that have filled up again and reports how many
+// were removed.
+func (s *MemoryRateLimitStore) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RunJanitor sweeps the store every interval until ctx is cancelled.
+func (s *MemoryRateLimitStore) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := s.Sweep(); n > 0 {
+				slog.Debug("rate limit buckets dropped", "count", n)
+			}
+		}
+	}
+}
+
+// RateLimit gives every client, as told apart by keys, its own token bucket
+// for the routes of group. A request is let through only if every bucket
+// keys returns has a token, and then draws one from each. Every response
+// carries the RateLimit-* headers of the tightest bucket; a refused request
+// is answered with 429 and Retry-After.
+func RateLimit(store RateLimitStore, group string, rate Rate, keys func(*http.Request) []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clients := keys(r)
+			scoped := make([]string, len(clients))
+			for i, client := range clients {
+				scoped[i] = group + "|" + client
+			}
+			d := store.Take(scoped, rate)
+
+			h := w.Header()
+			h.Set(RateLimitLimitHeader, strconv.Itoa(d.Limit))
+			h.Set(RateLimitRemainingHeader, strconv.Itoa(d.Remaining))
+			h.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(d.Reset)))
+
+			if !d.Allowed {
+				retry := max(ceilSeconds(d.RetryAfter), 1)
+				logger.FromContext(r.Context()).Warn("rate limit exceeded",
+					"middleware", "ratelimit",
+					"group", group,
+					"client", strings.Join(clients, " "),
+					"path", r.URL.Path,
+					"retry_after_s", retry,
+				)
+				h.Set(RetryAfterHeader, strconv.Itoa(retry))
+				writeError(w, r, http.StatusTooManyRequests, "rate_limited", retry)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tighter reports whether a limits the client more than b.
+func tighter(a, b RateDecision) bool {
+	switch {
+	case a.Allowed != b.Allowed:
+		return !a.Allowed
+	case !a.Allowed:
+		return a.RetryAfter > b.RetryAfter
+	default:
+		return a.Remaining < b.Remaining
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimitKey returns how clients are told apart: by IP address, by
+// X-API-Key, or by user, i.e. the credentials in Authorization. The service
+// does not check those credentials, so a client could make up a new one for
+// every request; in the api_key and user modes a request therefore also
+// draws from the bucket of its IP address, and the modes only tell clients
+// behind one address apart once an authenticating proxy in front of the
+// service has checked the credentials. Credentials are hashed so that the
+// store never holds them.
+func RateLimitKey(kind string, trustedProxies []netip.Prefix) func(*http.Request) []string {
+	byIP := func(r *http.Request) []string { return []string{"ip:" + ClientIP(r, trustedProxies)} }
+	byHeader := func(prefix, header string) func(*http.Request) []string {
+		return func(r *http.Request) []string {
+			if v := r.Header.Get(header); v != "" {
+				sum := sha256.Sum256([]byte(v))
+				return append([]string{prefix + hex.EncodeToString(sum[:12])}, byIP(r)...)
+			}
+			return byIP(r)
+		}
+	}
+
+	switch kind {
+	case "api_key":
+		return byHeader("key:", APIKeyHeader)
+	case "user":
+		return byHeader("user:", "Authorization")
+	default:
+		return byIP
+	}
+}
+
+// ClientIP is the address the request came from. X-Forwarded-For is only
+// believed when the connection comes from a trusted proxy; it is then read
+// from the right, skipping the trusted proxies, so a client cannot pick its
+// address by sending the header itself.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !trusted(addr, trustedProxies) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !trusted(addr, trustedProxies) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func trusted(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies accepts addresses and CIDR ranges.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
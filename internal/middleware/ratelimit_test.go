@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore_TokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	rate := Rate{Requests: 60, Per: time.Minute, Burst: 3}
+
+	for i := range 3 {
+		d := store.Take([]string{"client"}, rate)
+		require.True(t, d.Allowed, "request %d is within the burst", i)
+		assert.Equal(t, 2-i, d.Remaining)
+	}
+
+	d := store.Take([]string{"client"}, rate)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 3, d.Limit)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 3*time.Second, d.Reset)
+	assert.True(t, store.Take([]string{"other"}, rate).Allowed, "clients have their own buckets")
+
+	now = now.Add(time.Second)
+	assert.True(t, store.Take([]string{"client"}, rate).Allowed, "one token per second comes back")
+	assert.False(t, store.Take([]string{"client"}, rate).Allowed)
+}
+
+func TestMemoryRateLimitStore_FullBucketsAreSwept(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	rate := Rate{Requests: 1, Per: time.Minute}
+
+	store.Take([]string{"client"}, rate)
+	assert.Zero(t, store.Sweep())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, store.Sweep())
+}
+
+func TestRateLimit_RefusesWith429(t *testing.T) {
+	h := RateLimit(NewMemoryRateLimitStore(), "booking", Rate{Requests: 1, Per: time.Minute}, RateLimitKey("ip", nil))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }),
+	)
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, httptest.NewRequest(http.MethodPost, "/CreateBooking", nil))
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "1", first.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", first.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "60", first.Header().Get(RateLimitResetHeader))
+
+	second := httptest.NewRecorder()
+	h.ServeHTTP(second, httptest.NewRequest(http.MethodPost, "/CreateBooking", nil))
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+	assert.Equal(t, "60", second.Header().Get(RetryAfterHeader))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"rate_limited","error":"rate limit exceeded, retry in 60 s"}`, second.Body.String())
+}
+
+func TestRateLimitKey(t *testing.T) {
+	req := func(header, value string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.7:4242"
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		return r
+	}
+
+	assert.Equal(t, []string{"ip:203.0.113.7"}, RateLimitKey("ip", nil)(req(APIKeyHeader, "k1")))
+
+	byKey := RateLimitKey("api_key", nil)
+	assert.Equal(t, byKey(req(APIKeyHeader, "k1")), byKey(req(APIKeyHeader, "k1")))
+	assert.NotEqual(t, byKey(req(APIKeyHeader, "k1"))[0], byKey(req(APIKeyHeader, "k2"))[0])
+	assert.NotContains(t, byKey(req(APIKeyHeader, "k1"))[0], "k1", "keys are not kept in clear")
+	assert.Equal(t, "ip:203.0.113.7", byKey(req(APIKeyHeader, "k1"))[1], "the address is limited as well")
+	assert.Equal(t, []string{"ip:203.0.113.7"}, byKey(req("", "")))
+
+	byUser := RateLimitKey("user", nil)
+	assert.Regexp(t, "^user:", byUser(req("Authorization", "Bearer token"))[0])
+}
+
+func TestRateLimit_MadeUpKeysShareTheAddressBucket(t *testing.T) {
+	h := RateLimit(NewMemoryRateLimitStore(), "booking", Rate{Requests: 2, Per: time.Minute}, RateLimitKey("api_key", nil))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }),
+	)
+	call := func(remote, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/CreateBooking", nil)
+		req.RemoteAddr = remote
+		req.Header.Set(APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for range 2 {
+		assert.Equal(t, http.StatusCreated, call("203.0.113.7:1", "k1").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, call("203.0.113.7:1", "k1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, call("203.0.113.7:1", "made-up").Code, "a new key does not get around the address limit")
+	assert.Equal(t, http.StatusTooManyRequests, call("198.51.100.1:1", "k1").Code, "nor does a new address get around the key limit")
+	assert.Equal(t, http.StatusCreated, call("198.51.100.1:1", "k2").Code)
+}
+
+func TestRateLimit_RefusalsSpendNoTokens(t *testing.T) {
+	h := RateLimit(NewMemoryRateLimitStore(), "booking", Rate{Requests: 2, Per: time.Minute}, RateLimitKey("api_key", nil))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }),
+	)
+	call := func(remote, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/CreateBooking", nil)
+		req.RemoteAddr = remote
+		req.Header.Set(APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusCreated, call("203.0.113.7:1", "k1").Code)
+	assert.Equal(t, http.StatusCreated, call("203.0.113.7:1", "k2").Code)
+	for range 3 {
+		assert.Equal(t, http.StatusTooManyRequests, call("203.0.113.7:1", "k3").Code, "the address bucket is empty")
+	}
+
+	first := call("198.51.100.1:1", "k3")
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "1", first.Header().Get(RateLimitRemainingHeader), "the key bucket was full")
+	assert.Equal(t, http.StatusCreated, call("198.51.100.1:1", "k3").Code)
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:1000", "", "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:1000", "198.51.100.1", "203.0.113.7"},
+		{"behind a trusted proxy", "10.1.2.3:1000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed hop left of the real client", "10.1.2.3:1000", "1.1.1.1, 198.51.100.1, 192.0.2.1", "198.51.100.1"},
+		{"invalid hop stops the walk", "10.1.2.3:1000", "198.51.100.1, junk", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			assert.Equal(t, tt.want, ClientIP(r, proxies))
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies([]string{"10.1.2.3/8", "::1"})
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}, got)
+
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	assert.ErrorContains(t, err, "proxy.local")
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"golangHotelProject/internal/logger"
+)
+
+// Recover turns a panic in next into a 500 with the internal_error envelope
+// and logs it with the stack through the request-scoped logger. If the
+// handler had already started the response, the status can no longer change
+// and the connection is left to net/http. http.ErrAbortHandler is passed on:
+// it is how handlers abort on purpose.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := NewStatusRecorder(w)
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+
+			logger.FromContext(r.Context()).Error("panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(p),
+				"stack", string(debug.Stack()),
+			)
+			if rec.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+
+			writeError(w, r, http.StatusInternalServerError, "internal_error")
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
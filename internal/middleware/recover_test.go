@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golangHotelProject/internal/i18n"
+	"golangHotelProject/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover_AnswersJSON500AndLogsTheStack(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(RequestID(slog.New(slog.NewJSONHandler(&buf, nil))), Recover)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id *int
+		_ = *id
+	}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/PatchBookingByID", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"internal_error","error":"internal error","request_id":"req-1"}`, rec.Body.String())
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "panic serving request", line["msg"])
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Contains(t, line["panic"], "nil pointer dereference")
+	assert.Contains(t, line["stack"], "recover_test.go")
+}
+
+func TestRecover_AnswersInTheLanguageOfTheRequest(t *testing.T) {
+	h := Chain(Language(i18n.English), Recover)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "ru")
+	req = req.WithContext(logger.NewContext(req.Context(), slog.New(slog.DiscardHandler)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"code":"internal_error","error":"внутренняя ошибка"}`, rec.Body.String())
+}
+
+func TestRecover_AbortsWhenTheResponseHasStarted(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("late")
+	}))
+	ctx := logger.NewContext(httptest.NewRequest(http.MethodGet, "/", nil).Context(), slog.New(slog.DiscardHandler))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	})
+}
+
+func TestRecover_PassesErrAbortHandlerOn(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"golangHotelProject/internal/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds a client supplied ID, which ends up in every log
+// line of the request.
+const maxRequestIDLen = 128
+
+// RequestID takes the request ID from X-Request-ID or makes one up, echoes
+// it in the response and stores a logger carrying it as request_id in the
+// request context (see logger.FromContext).
+func RequestID(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			l := base.With("request_id", id)
+			next.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), l)))
+		})
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, which
+// covers UUIDs and the IDs proxies generate, and keeps log injection out.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -9,4 +9,5 @@ type Booking struct {
 	Start_date time.Time `json:"start_date"`
 	End_date   time.Time `json:"end_date"`
 	Status     string    `json:"status"`
+	Version    int       `json:"version"`
 }
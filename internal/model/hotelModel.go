@@ -9,4 +9,5 @@ type Room struct {
 	SleepingPlaces int    `json:"sleeping_places"`
 	RoomType       string `json:"room_type"`
 	NeedCleaning   bool   `json:"need_cleaning"`
+	Version        int    `json:"version"`
 }
@@ -6,33 +6,41 @@ import (
 	"errors"
 	"fmt"
 	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/logger"
 	"golangHotelProject/internal/model"
 	"golangHotelProject/internal/repository/db"
-	"log"
+	"time"
 )
 
 type BookingRepository interface {
-	CreateBooking(ctx context.Context, b model.Booking) error
+	CreateBooking(ctx context.Context, b model.Booking) (model.Booking, error)
 	GettingStatus(ctx context.Context, guest_id int) (bool, error)
 	ArrivalStatusOfRoom(ctx context.Context, RoomID int) (bool, error)
+	HasOverlap(ctx context.Context, roomID int, start, end time.Time, excludeID int) (bool, error)
 	ReadBookingByID(ctx context.Context, id int) (model.Booking, error)
-	PatchBooking(ctx context.Context, b dto.BookingPatch) error
+	PatchBooking(ctx context.Context, version int, b dto.BookingPatch) (int, error)
 	ListColumn(ctx context.Context) ([]model.Booking, error)
 	FilterBookings(ctx context.Context, filter map[string]interface{}) (map[string][]int, error)
-	DeleteBooking(ctx context.Context, id int) error
+	DeleteBooking(ctx context.Context, id, version int) error
 }
 
 type PgBookingRepository struct {
-	DB *sql.DB
+	DB db.DBTX
 }
 
-func (r *PgBookingRepository) CreateBooking(ctx context.Context, b model.Booking) error {
-	_, err := r.DB.ExecContext(ctx, `INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
-	VALUES($1, $2, $3, $4, $5)`, b.RoomID, b.GuestID, b.Start_date, b.End_date, b.Status)
+func (r *PgBookingRepository) CreateBooking(ctx context.Context, b model.Booking) (model.Booking, error) {
+	const q = `INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING id, room_id, guest_id, start_date, end_date, status, version`
+
+	var created model.Booking
+	err := r.DB.QueryRowContext(ctx, q, b.RoomID, b.GuestID, b.Start_date, b.End_date, b.Status).
+		Scan(&created.ID, &created.RoomID, &created.GuestID, &created.Start_date, &created.End_date, &created.Status, &created.Version)
 	if err != nil {
-		log.Printf("ERROR inserting booking: %v", err)
+		logger.FromContext(ctx).Error("error inserting booking", "error", err)
+		return model.Booking{}, translatePgError(err)
 	}
-	return err
+	return created, nil
 }
 
 func (r *PgBookingRepository) GettingStatus(ctx context.Context, guest_id int) (bool, error) {
@@ -65,48 +73,59 @@ func (r *PgBookingRepository) ArrivalStatusOfRoom(ctx context.Context, RoomID in
 	return isTrue, nil
 }
 
+// HasOverlap reports whether another booking of the room shares at least one
+// night with [start, end). excludeID skips the booking being edited.
+func (r *PgBookingRepository) HasOverlap(ctx context.Context, roomID int, start, end time.Time, excludeID int) (bool, error) {
+	const q = `SELECT EXISTS (
+		SELECT 1 FROM bookings
+		WHERE room_id = $1 AND id <> $4 AND daterange(start_date, end_date) && daterange($2::date, $3::date)
+	)`
+
+	var overlaps bool
+	if err := r.DB.QueryRowContext(ctx, q, roomID, start, end, excludeID).Scan(&overlaps); err != nil {
+		return false, err
+	}
+	return overlaps, nil
+}
+
 func (r *PgBookingRepository) ReadBookingByID(ctx context.Context, id int) (model.Booking, error) {
-	const q = `SELECT id, room_id, guest_id, start_date, end_date, status FROM bookings WHERE id = $1`
+	const q = `SELECT id, room_id, guest_id, start_date, end_date, status, version FROM bookings WHERE id = $1`
 	row := r.DB.QueryRowContext(ctx, q, id)
 
 	var b model.Booking
 
-	err := row.Scan(&b.ID, &b.RoomID, &b.GuestID, &b.Start_date, &b.End_date, &b.Status)
+	err := row.Scan(&b.ID, &b.RoomID, &b.GuestID, &b.Start_date, &b.End_date, &b.Status, &b.Version)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return model.Booking{}, err
-		}
+		return model.Booking{}, err
 	}
 	return b, nil
 }
 
-func (r *PgBookingRepository) PatchBooking(ctx context.Context, b dto.BookingPatch) error {
-	const q = `UPDATE bookings SET room_id = $1, guest_id = $2, start_date = $3, end_date = $4, status = $5 WHERE id = $6`
-	rows, err := r.DB.ExecContext(ctx, q, b.RoomID, b.GuestID, b.Start_date, b.End_date, b.Status, b.ID)
-	if err != nil {
-		return err
-	}
-	rowsAffected, err := rows.RowsAffected()
-	if err != nil {
-		return err
-	}
+func (r *PgBookingRepository) PatchBooking(ctx context.Context, version int, b dto.BookingPatch) (int, error) {
+	const q = `UPDATE bookings SET room_id = $1, guest_id = $2, start_date = $3, end_date = $4, status = $5, version = version + 1
+	WHERE id = $6 AND version = $7 RETURNING version`
 
-	if rowsAffected == 0 {
-		return errors.New("no rows")
+	var newVersion int
+	err := r.DB.QueryRowContext(ctx, q, b.RoomID, b.GuestID, b.Start_date, b.End_date, b.Status, b.ID, version).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, r.missOrStale(ctx, *b.ID)
+		}
+		return 0, translatePgError(err)
 	}
-	return nil
+	return newVersion, nil
 }
 
 func (r *PgBookingRepository) ListColumn(ctx context.Context) ([]model.Booking, error) {
 
-	rows, err := r.DB.QueryContext(ctx, `SELECT id, room_id, guest_id, start_date, end_date, status FROM bookings`)
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, room_id, guest_id, start_date, end_date, status, version FROM bookings`)
 	if err != nil {
 		return nil, err
 	}
 
 	defer func() {
 		if err := rows.Close(); err != nil {
-			log.Printf("error closing rows: %v", err)
+			logger.FromContext(ctx).Error("error closing rows", "error", err)
 		}
 	}()
 
@@ -115,7 +134,7 @@ func (r *PgBookingRepository) ListColumn(ctx context.Context) ([]model.Booking,
 	for rows.Next() {
 		var b model.Booking
 
-		err := rows.Scan(&b.ID, &b.RoomID, &b.GuestID, &b.Start_date, &b.End_date, &b.Status)
+		err := rows.Scan(&b.ID, &b.RoomID, &b.GuestID, &b.Start_date, &b.End_date, &b.Status, &b.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -132,37 +151,45 @@ func (r *PgBookingRepository) ListColumn(ctx context.Context) ([]model.Booking,
 }
 
 func (r *PgBookingRepository) FilterBookings(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	if err := checkFilterColumns(filter, bookingColumns(model.Booking{})); err != nil {
+		return nil, err
+	}
 	responses := make(map[string][]int)
 	for column, value := range filter {
-		query := fmt.Sprintf("SELECT id FROM bookings WHERE %s = $1", column)
-		rows, err := db.DB.QueryContext(ctx, query, value)
+		ids, err := filterIDsByColumn(ctx, r.DB, "bookings", column, value)
 		if err != nil {
 			return nil, err
 		}
-
-		defer func() {
-			if err := rows.Close(); err != nil {
-				log.Printf("error closing rows: %v", err)
-			}
-		}()
-
-		for rows.Next() {
-			var currentID int
-			if err := rows.Scan(&currentID); err != nil {
-				return nil, err
-			}
-
-			strValue := fmt.Sprintf("%v", value)
-			responses[strValue] = append(responses[strValue], currentID)
+		if len(ids) > 0 {
+			key := fmt.Sprintf("%v", value)
+			responses[key] = append(responses[key], ids...)
 		}
 	}
 	return responses, nil
 }
 
-func (r *PgBookingRepository) DeleteBooking(ctx context.Context, id int) error {
-	_, err := db.DB.ExecContext(ctx, `DELETE FROM bookings WHERE id = $1`, id)
+func (r *PgBookingRepository) DeleteBooking(ctx context.Context, id, version int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM bookings WHERE id = $1 AND version = $2`, id, version)
 	if err != nil {
 		return err
 	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missOrStale(ctx, id)
+	}
 	return nil
 }
+
+// missOrStale tells apart the two reasons a conditional write can touch no
+// rows: the booking is gone, or somebody else bumped its version first.
+func (r *PgBookingRepository) missOrStale(ctx context.Context, id int) error {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM bookings WHERE id = $1`, id).Scan(&one)
+	if err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"testing"
+
+	"golangHotelProject/internal/repository"
+	"golangHotelProject/internal/repository/db/dbtest"
+	"golangHotelProject/internal/repository/repotest"
+)
+
+func TestMemoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		store := repository.NewMemoryStore()
+		return repotest.Backend{
+			Rooms:    store.Rooms(),
+			Bookings: store.Bookings(),
+			UoW:      &repository.MemoryUnitOfWork{Store: store},
+		}
+	})
+}
+
+func TestSQLiteContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		conn := dbtest.SQLite(t)
+		return repotest.Backend{
+			Rooms:    &repository.SQLiteRoomRepository{DB: conn},
+			Bookings: &repository.SQLiteBookingRepository{DB: conn},
+			UoW:      &repository.SQLiteUnitOfWork{DB: conn},
+		}
+	})
+}
+
+// TestPostgresContract needs TEST_DATABASE_URL; see dbtest.Postgres.
+func TestPostgresContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		conn := dbtest.Postgres(t)
+		return repotest.Backend{
+			Rooms:    &repository.PgRoomRepository{DB: conn},
+			Bookings: &repository.PgBookingRepository{DB: conn},
+			UoW:      &repository.PgUnitOfWork{DB: conn},
+		}
+	})
+}
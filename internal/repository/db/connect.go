@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// PoolOptions tunes the database/sql connection pool. Zero values keep the
+// database/sql defaults, except that MaxOpenConns 0 means unlimited.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (o PoolOptions) Apply(conn *sql.DB) {
+	conn.SetMaxOpenConns(o.MaxOpenConns)
+	conn.SetMaxIdleConns(o.MaxIdleConns)
+	conn.SetConnMaxLifetime(o.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+}
+
+// Backoff controls how WaitReady retries: the delay starts at Initial and
+// doubles up to Max, and the whole wait gives up after Timeout.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Timeout time.Duration
+}
+
+// next returns the delay after d with up to 20% jitter, so that replicas
+// restarted together do not hit the database in lockstep.
+func (b Backoff) next(d time.Duration) time.Duration {
+	d *= 2
+	if d > b.Max {
+		d = b.Max
+	}
+	return d - time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// WaitReady pings until the database answers, backing off between attempts.
+// It gives up when b.Timeout passes or ctx is done and returns the last ping
+// error.
+func WaitReady(ctx context.Context, p Pinger, b Backoff, log *slog.Logger) error {
+	if log == nil {
+		log = slog.Default()
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	delay := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := p.PingContext(ctx)
+		if err == nil {
+			log.Info("database connection successful", "attempts", attempt, "elapsed", time.Since(start).String())
+			return nil
+		}
+
+		if ctx.Err() == nil {
+			log.Warn("database not reachable, retrying", "attempt", attempt, "retry_in", delay.String(), "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempt(s) in %s: %w",
+				attempt, time.Since(start).Round(time.Millisecond), errors.Join(err, ctx.Err()))
+		case <-time.After(delay):
+		}
+		delay = b.next(delay)
+	}
+}
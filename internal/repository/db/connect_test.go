@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyPinger struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyPinger) PingContext(ctx context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+var quietLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestWaitReady_RetriesUntilTheDatabaseAnswers(t *testing.T) {
+	p := &flakyPinger{failures: 3}
+
+	err := WaitReady(context.Background(), p, Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Timeout: time.Second}, quietLog)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, p.calls)
+}
+
+func TestWaitReady_GivesUpAfterTimeout(t *testing.T) {
+	p := &flakyPinger{failures: 1 << 30}
+
+	start := time.Now()
+	err := WaitReady(context.Background(), p, Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond, Timeout: 100 * time.Millisecond}, quietLog)
+
+	assert.ErrorContains(t, err, "connection refused")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Greater(t, p.calls, 2)
+}
+
+func TestBackoff_NextDoublesUpToMax(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+
+	d := b.next(100 * time.Millisecond)
+	assert.InDelta(t, 200*time.Millisecond, d, float64(40*time.Millisecond))
+	assert.LessOrEqual(t, d, 200*time.Millisecond)
+
+	d = b.next(800 * time.Millisecond)
+	assert.LessOrEqual(t, d, time.Second)
+	assert.GreaterOrEqual(t, d, 800*time.Millisecond)
+}
@@ -1,51 +1,59 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
-
-func InitDB() error {
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	user := os.Getenv("DB_USER")
-	if user == "" {
-		user = "postgres"
-	}
-	password := os.Getenv("DB_PASSWORD")
-	if password == "" {
-		password = "123456789"
-	}
-	dbname := os.Getenv("DB_NAME")
-	if dbname == "" {
-		dbname = "hotel"
-	}
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "5432"
-	}
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
 
-	connStr := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host, user, password, dbname, port)
+// DBTX is the part of *sql.DB and *sql.Tx the repositories use, so the same
+// repository can run either on the pool or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	var err error
-	DB, err = sql.Open("postgres", connStr)
+// OpenPostgres prepares a pool for a lib/pq connection string. It does not
+// connect yet; use WaitReady to wait until the server accepts connections.
+func OpenPostgres(dsn string, pool PoolOptions) (*sql.DB, error) {
+	conn, err := sql.Open(DriverPostgres, dsn)
 	if err != nil {
-		return fmt.Errorf("database connection error: %v", err)
+		return nil, fmt.Errorf("database connection error: %v", err)
+	}
+	pool.Apply(conn)
+	return conn, nil
+}
+
+// OpenSQLite opens the SQLite database at path with foreign keys on and
+// write transactions that take the lock up front, so that the check-then-write
+// steps of a unit of work cannot interleave.
+func OpenSQLite(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	if path != ":memory:" {
+		dsn += "&_pragma=journal_mode(WAL)"
 	}
 
-	err = DB.Ping()
+	conn, err := sql.Open(DriverSQLite, dsn)
 	if err != nil {
-		return fmt.Errorf("checking connection error: %v", err)
+		return nil, fmt.Errorf("database connection error: %v", err)
+	}
+	if path == ":memory:" {
+		// Every connection to :memory: is a database of its own.
+		conn.SetMaxOpenConns(1)
 	}
 
-	fmt.Println("database connection successful")
-	return nil
+	if err := conn.Ping(); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("checking connection error: %v", err)
+	}
+	return conn, nil
 }
@@ -0,0 +1,85 @@
+// Package dbtest opens throwaway, fully migrated databases for tests.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/repository/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Postgres connects to TEST_DATABASE_URL and gives the test its own schema,
+// dropped afterwards, so tests never see each other's rows. The test is
+// skipped when TEST_DATABASE_URL is not set.
+func Postgres(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open(db.DriverPostgres, dsn)
+	require.NoError(t, err)
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(`CREATE EXTENSION IF NOT EXISTS btree_gist`)
+	require.NoError(t, err)
+	_, err = admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+
+	conn, err := sql.Open(db.DriverPostgres, withSearchPath(dsn, schema+",public"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_, _ = admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	migrate(t, conn, db.DriverPostgres)
+	return conn
+}
+
+// SQLite opens a migrated database file in the test's temp dir.
+func SQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	conn, err := db.OpenSQLite(filepath.Join(t.TempDir(), "hotel.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	migrate(t, conn, db.DriverSQLite)
+	return conn
+}
+
+func migrate(t testing.TB, conn *sql.DB, driver string) {
+	t.Helper()
+
+	migrator, err := db.NewMigrator(conn, driver)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+}
+
+func withSearchPath(dsn, path string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", path)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + path
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_later.up.sql":    {Data: []byte("later up")},
+		"m/0002_second.up.sql":   {Data: []byte("second up")},
+		"m/0002_second.down.sql": {Data: []byte("second down")},
+		"m/0001_first.up.sql":    {Data: []byte("first up")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, []int{1, 2, 10}, []int{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+	assert.Equal(t, "second", migrations[1].Name)
+	assert.Equal(t, "second down", migrations[1].Down)
+	assert.Empty(t, migrations[0].Down)
+}
+
+func TestLoadMigrations_RejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"unknown file", fstest.MapFS{"m/readme.md": {}}},
+		{"down without up", fstest.MapFS{"m/0001_first.down.sql": {Data: []byte("x")}}},
+		{"two names for one version", fstest.MapFS{
+			"m/0001_first.up.sql":   {Data: []byte("x")},
+			"m/0001_other.down.sql": {Data: []byte("x")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys, "m")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, driver := range []string{DriverPostgres, DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			m, err := NewMigrator(nil, driver)
+
+			require.NoError(t, err)
+			require.NotEmpty(t, m.Migrations)
+			for i, mig := range m.Migrations {
+				assert.Equal(t, i+1, mig.Version, "migrations must be numbered without gaps")
+				assert.NotEmpty(t, mig.Down, "migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+			assert.NotEmpty(t, m.Seed)
+		})
+	}
+
+	_, err := NewMigrator(nil, "oracle")
+	assert.Error(t, err)
+}
@@ -1,3 +1,5 @@
+-- Tables as the original init.sql created them. IF NOT EXISTS lets databases
+-- that were bootstrapped from init.sql adopt migrations without changes.
 CREATE TABLE IF NOT EXISTS rooms (
     id SERIAL PRIMARY KEY,
     number INT NOT NULL UNIQUE,
@@ -9,12 +11,6 @@ CREATE TABLE IF NOT EXISTS rooms (
     need_cleaning BOOLEAN NOT NULL DEFAULT FALSE
 );
 
-INSERT INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
-VALUES
-    (1, 1, FALSE, 1, 2, 'Standard', FALSE),
-    (2, 1, TRUE, 2, 4, 'Deluxe', TRUE),
-    (3, 1, FALSE, 3, 3, 'Suite', FALSE);
-    
 CREATE TABLE IF NOT EXISTS bookings (
     id SERIAL PRIMARY KEY,
     room_id INT NOT NULL,
@@ -25,9 +21,3 @@ CREATE TABLE IF NOT EXISTS bookings (
     CHECK (start_date < end_date),
     CONSTRAINT fk_bookings_room FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
 );
-
-INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
-VALUES
-    (1, 1,  '2025-10-17', '2025-11-17', TRUE),
-    (2, 2,   '2030-10-31', '2030-11-20', FALSE),
-    (3, 3, '2025-12-20', '2026-01-11', FALSE);
@@ -0,0 +1,2 @@
+ALTER TABLE bookings DROP COLUMN IF EXISTS version;
+ALTER TABLE rooms DROP COLUMN IF EXISTS version;
@@ -0,0 +1,27 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+DO $$
+DECLARE
+    overlapping text;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'bookings_no_overlap' AND conrelid = 'bookings'::regclass) THEN
+        -- Existing double bookings would make ADD CONSTRAINT fail with a bare
+        -- exclusion violation, so name them before trying.
+        SELECT string_agg(format('%s and %s (room %s)', a.id, b.id, a.room_id), ', ' ORDER BY a.id, b.id)
+          INTO overlapping
+          FROM bookings a
+          JOIN bookings b
+            ON a.room_id = b.room_id
+           AND a.id < b.id
+           AND daterange(a.start_date, a.end_date) && daterange(b.start_date, b.end_date);
+
+        IF overlapping IS NOT NULL THEN
+            RAISE EXCEPTION 'cannot add bookings_no_overlap: these bookings overlap: %', overlapping
+                USING HINT = 'Move or delete one booking of each pair, then run the migration again.';
+        END IF;
+
+        ALTER TABLE bookings ADD CONSTRAINT bookings_no_overlap
+            EXCLUDE USING gist (room_id WITH =, daterange(start_date, end_date) WITH &&);
+    END IF;
+END
+$$;
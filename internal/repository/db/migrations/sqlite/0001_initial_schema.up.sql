@@ -0,0 +1,26 @@
+CREATE TABLE rooms (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    number INTEGER NOT NULL UNIQUE,
+    room_count INTEGER NOT NULL DEFAULT 1,
+    is_occupied BOOLEAN NOT NULL DEFAULT FALSE,
+    floor INTEGER NOT NULL,
+    sleeping_places INTEGER NOT NULL DEFAULT 1,
+    room_type TEXT NOT NULL CHECK (room_type IN ('Standard', 'Deluxe', 'Suite')),
+    need_cleaning BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Dates are stored as YYYY-MM-DD text so that they compare in calendar order.
+CREATE TABLE bookings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    room_id INTEGER NOT NULL,
+    guest_id INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (start_date < end_date),
+    CONSTRAINT fk_bookings_room FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookings_room_dates ON bookings (room_id, start_date, end_date);
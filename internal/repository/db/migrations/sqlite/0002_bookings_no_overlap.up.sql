@@ -0,0 +1,21 @@
+-- SQLite has no exclusion constraints; these triggers reject a booking that
+-- shares a night with another booking of the same room.
+CREATE TRIGGER bookings_no_overlap_insert
+BEFORE INSERT ON bookings
+WHEN EXISTS (
+    SELECT 1 FROM bookings
+    WHERE room_id = NEW.room_id AND start_date < NEW.end_date AND NEW.start_date < end_date
+)
+BEGIN
+    SELECT RAISE(ABORT, 'bookings_no_overlap');
+END;
+
+CREATE TRIGGER bookings_no_overlap_update
+BEFORE UPDATE OF room_id, start_date, end_date ON bookings
+WHEN EXISTS (
+    SELECT 1 FROM bookings
+    WHERE room_id = NEW.room_id AND id <> NEW.id AND start_date < NEW.end_date AND NEW.start_date < end_date
+)
+BEGIN
+    SELECT RAISE(ABORT, 'bookings_no_overlap');
+END;
@@ -0,0 +1,17 @@
+-- Demo data. Safe to run more than once.
+INSERT INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
+VALUES
+    (1, 1, FALSE, 1, 2, 'Standard', FALSE),
+    (2, 1, TRUE, 2, 4, 'Deluxe', TRUE),
+    (3, 1, FALSE, 3, 3, 'Suite', FALSE)
+ON CONFLICT (number) DO NOTHING;
+
+INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
+SELECT r.id, s.guest_id, s.start_date, s.end_date, s.status
+FROM (VALUES
+    (1, 1, DATE '2025-10-17', DATE '2025-11-17', TRUE),
+    (2, 2, DATE '2030-10-31', DATE '2030-11-20', FALSE),
+    (3, 3, DATE '2025-12-20', DATE '2026-01-11', FALSE)
+) AS s(number, guest_id, start_date, end_date, status)
+JOIN rooms r ON r.number = s.number
+ON CONFLICT DO NOTHING;
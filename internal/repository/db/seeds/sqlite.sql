@@ -0,0 +1,16 @@
+-- Demo data. Safe to run more than once.
+INSERT OR IGNORE INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
+VALUES
+    (1, 1, FALSE, 1, 2, 'Standard', FALSE),
+    (2, 1, TRUE, 2, 4, 'Deluxe', TRUE),
+    (3, 1, FALSE, 3, 3, 'Suite', FALSE);
+
+INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
+SELECT r.id, s.guest_id, s.start_date, s.end_date, s.status
+FROM (
+    SELECT 1 AS number, 1 AS guest_id, '2025-10-17' AS start_date, '2025-11-17' AS end_date, TRUE AS status
+    UNION ALL SELECT 2, 2, '2030-10-31', '2030-11-20', FALSE
+    UNION ALL SELECT 3, 3, '2025-12-20', '2026-01-11', FALSE
+) AS s
+JOIN rooms r ON r.number = s.number
+WHERE NOT EXISTS (SELECT 1 FROM bookings b WHERE b.room_id = r.id);
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("golangHotelProject/internal/repository/db")
+
+// Traced wraps inner so that every statement runs in a client span carrying
+// the SQL text. The statements hold $N placeholders only, never values.
+func Traced(inner DBTX, driver string) DBTX {
+	system := semconv.DBSystemNamePostgreSQL
+	if driver == DriverSQLite {
+		system = semconv.DBSystemNameSQLite
+	}
+	return tracedDB{inner: inner, system: system}
+}
+
+type tracedDB struct {
+	inner  DBTX
+	system attribute.KeyValue
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	res, err := t.inner.ExecContext(ctx, query, args...)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.inner.QueryContext(ctx, query, args...)
+	end(span, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.inner.QueryRowContext(ctx, query, args...)
+	end(span, row.Err())
+	return row
+}
+
+func (t tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, spanName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.system, semconv.DBQueryText(query)),
+	)
+}
+
+// spanName is the operation of the statement, e.g. "SELECT" or "INSERT".
+func spanName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "sql"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func end(span trace.Span, err error) {
+	// No rows is an answer, not a failure.
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrVersionMismatch is returned by conditional writes when the row exists
+// but its version has moved since the caller read it.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrConflict is returned when the database rejects a write because it would
+// break a uniqueness or exclusion rule, e.g. two bookings of one room overlap.
+var ErrConflict = errors.New("conflicts with existing data")
+
+// UnknownColumnError is returned by the filters for a key that is not a
+// column of the table. Filter keys come from request bodies, so they are
+// checked against the known columns before any query is built from them.
+type UnknownColumnError struct {
+	Column string
+}
+
+func (e *UnknownColumnError) Error() string {
+	return fmt.Sprintf("column %q does not exist", e.Column)
+}
+
+// checkFilterColumns rejects filter keys that columns, the column map of a
+// zero row, does not hold.
+func checkFilterColumns(filter map[string]interface{}, columns map[string]any) error {
+	for column := range filter {
+		if _, ok := columns[column]; !ok {
+			return &UnknownColumnError{Column: column}
+		}
+	}
+	return nil
+}
+
+const (
+	pgUniqueViolation    = "23505"
+	pgExclusionViolation = "23P01"
+)
+
+// translatePgError turns constraint violations into ErrConflict and leaves
+// every other error as it is.
+func translatePgError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pgUniqueViolation, pgExclusionViolation:
+		return errors.Join(ErrConflict, err)
+	}
+	return err
+}
+
+// translateSQLiteError is translatePgError for SQLite. The overlap rule is a
+// trigger there, so it is recognized by the message the trigger raises.
+func translateSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return errors.Join(ErrConflict, err)
+	case sqlite3.SQLITE_CONSTRAINT_TRIGGER:
+		if strings.Contains(sqliteErr.Error(), "bookings_no_overlap") {
+			return errors.Join(ErrConflict, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,513 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	md "golangHotelProject/internal/model"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps rooms and bookings in process memory. It enforces the
+// same rules the Postgres schema does: unique room numbers, bookings that
+// reference an existing room and go away with it, no overlapping bookings of
+// one room, and row versions. Repositories and units of work created from one
+// store see the same data.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data memoryData
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: memoryData{
+		rooms:    make(map[int]md.Room),
+		bookings: make(map[int]md.Booking),
+	}}
+}
+
+func (s *MemoryStore) Rooms() *MemoryRoomRepository {
+	return &MemoryRoomRepository{tx: s}
+}
+
+func (s *MemoryStore) Bookings() *MemoryBookingRepository {
+	return &MemoryBookingRepository{tx: s}
+}
+
+func (s *MemoryStore) with(fn func(d *memoryData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&s.data)
+}
+
+type memoryData struct {
+	rooms         map[int]md.Room
+	bookings      map[int]md.Booking
+	lastRoomID    int
+	lastBookingID int
+}
+
+func (d *memoryData) clone() memoryData {
+	c := *d
+	c.rooms = make(map[int]md.Room, len(d.rooms))
+	for id, r := range d.rooms {
+		c.rooms[id] = r
+	}
+	c.bookings = make(map[int]md.Booking, len(d.bookings))
+	for id, b := range d.bookings {
+		c.bookings[id] = b
+	}
+	return c
+}
+
+// memoryTx is how repositories reach the data: either through the store's
+// lock, or through a unit of work that already holds it.
+type memoryTx interface {
+	with(fn func(d *memoryData) error) error
+}
+
+type heldLock struct {
+	data *memoryData
+}
+
+func (h heldLock) with(fn func(d *memoryData) error) error {
+	return fn(h.data)
+}
+
+// MemoryUnitOfWork runs fn with the store locked and puts the data back the
+// way it was if fn fails, so transactions are serialized and all-or-nothing.
+type MemoryUnitOfWork struct {
+	Store *MemoryStore
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) (err error) {
+	s := u.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			s.data = snapshot
+			panic(p)
+		}
+		if err != nil {
+			s.data = snapshot
+		}
+	}()
+
+	tx := heldLock{data: &s.data}
+	return fn(ctx, Repositories{
+		Rooms:    &MemoryRoomRepository{tx: tx},
+		Bookings: &MemoryBookingRepository{tx: tx},
+	})
+}
+
+func (d *memoryData) hasOverlap(roomID int, start, end time.Time, excludeID int) bool {
+	start, end = dateOnly(start), dateOnly(end)
+	for _, b := range d.bookings {
+		if b.RoomID != roomID || b.ID == excludeID {
+			continue
+		}
+		if b.Start_date.Before(end) && start.Before(b.End_date) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBooking normalizes b and applies the bookings table constraints.
+func (d *memoryData) checkBooking(b md.Booking) (md.Booking, error) {
+	b.Start_date, b.End_date = dateOnly(b.Start_date), dateOnly(b.End_date)
+	status, err := normalizeStatus(b.Status)
+	if err != nil {
+		return md.Booking{}, err
+	}
+	b.Status = status
+
+	if !b.Start_date.Before(b.End_date) {
+		return md.Booking{}, errors.New("booking violates check constraint: start_date < end_date")
+	}
+	if _, ok := d.rooms[b.RoomID]; !ok {
+		return md.Booking{}, fmt.Errorf("booking violates foreign key constraint: room %d does not exist", b.RoomID)
+	}
+	if d.hasOverlap(b.RoomID, b.Start_date, b.End_date, b.ID) {
+		return md.Booking{}, errors.Join(ErrConflict, fmt.Errorf("room %d is already booked for these dates", b.RoomID))
+	}
+	return b, nil
+}
+
+func (d *memoryData) numberTaken(number, exceptID int) bool {
+	for _, r := range d.rooms {
+		if r.Number == number && r.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIDs returns, for each filter entry, the ids of rows whose column
+// equals the value. key picks the map key the Postgres repository would use.
+func filterIDs[T any](rows map[int]T, filter map[string]interface{}, columns func(T) map[string]any, key func(column string, value any) string) (map[string][]int, error) {
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var zero T
+	if err := checkFilterColumns(filter, columns(zero)); err != nil {
+		return nil, err
+	}
+
+	responses := make(map[string][]int)
+	for column, value := range filter {
+		for _, id := range ids {
+			cols := columns(rows[id])
+			if field := cols[column]; sameValue(field, value) {
+				k := key(column, value)
+				responses[k] = append(responses[k], id)
+			}
+		}
+	}
+	return responses, nil
+}
+
+func sameValue(field, value any) bool {
+	switch f := field.(type) {
+	case bool:
+		if b, ok := value.(bool); ok {
+			return f == b
+		}
+		b, err := strconv.ParseBool(fmt.Sprint(value))
+		return err == nil && f == b
+	case time.Time:
+		return f.Format("2006-01-02") == fmt.Sprint(value) || f.Format(time.RFC3339) == fmt.Sprint(value)
+	}
+	return fmt.Sprint(field) == fmt.Sprint(value)
+}
+
+type MemoryRoomRepository struct {
+	tx memoryTx
+}
+
+func (r *MemoryRoomRepository) CreateRoom(ctx context.Context, room md.Room) (md.Room, error) {
+	var created md.Room
+	err := r.tx.with(func(d *memoryData) error {
+		if d.numberTaken(room.Number, 0) {
+			return errors.Join(ErrConflict, fmt.Errorf("room number %d already exists", room.Number))
+		}
+		switch room.RoomType {
+		case "Standard", "Deluxe", "Suite":
+		default:
+			return fmt.Errorf("room violates check constraint: room_type %q", room.RoomType)
+		}
+		d.lastRoomID++
+		room.ID = d.lastRoomID
+		room.Version = 1
+		d.rooms[room.ID] = room
+		created = room
+		return nil
+	})
+	return created, err
+}
+
+func (r *MemoryRoomRepository) ListRoom(ctx context.Context) ([]md.Room, error) {
+	var rooms []md.Room
+	err := r.tx.with(func(d *memoryData) error {
+		for _, room := range d.rooms {
+			rooms = append(rooms, room)
+		}
+		return nil
+	})
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].ID < rooms[j].ID })
+	return rooms, err
+}
+
+func (r *MemoryRoomRepository) GetRoomByID(ctx context.Context, id int) (md.Room, error) {
+	var room md.Room
+	err := r.tx.with(func(d *memoryData) error {
+		var ok bool
+		if room, ok = d.rooms[id]; !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	return room, err
+}
+
+func (r *MemoryRoomRepository) FilterRoom(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	var responses map[string][]int
+	err := r.tx.with(func(d *memoryData) error {
+		var err error
+		responses, err = filterIDs(d.rooms, filter, roomColumns, func(column string, _ any) string { return column })
+		return err
+	})
+	return responses, err
+}
+
+func roomColumns(r md.Room) map[string]any {
+	return map[string]any{
+		"id":              r.ID,
+		"number":          r.Number,
+		"room_count":      r.RoomCount,
+		"is_occupied":     r.IsOccupied,
+		"floor":           r.Floor,
+		"sleeping_places": r.SleepingPlaces,
+		"room_type":       r.RoomType,
+		"need_cleaning":   r.NeedCleaning,
+		"version":         r.Version,
+	}
+}
+
+func (r *MemoryRoomRepository) IsNumberExists(ctx context.Context, number int) (bool, error) {
+	var exists bool
+	err := r.tx.with(func(d *memoryData) error {
+		exists = d.numberTaken(number, 0)
+		return nil
+	})
+	return exists, err
+}
+
+func (r *MemoryRoomRepository) PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (int, error) {
+	if p == (dto.RoomPatch{}) {
+		return version, nil
+	}
+
+	var newVersion int
+	err := r.tx.with(func(d *memoryData) error {
+		room, ok := d.rooms[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		if room.Version != version {
+			return ErrVersionMismatch
+		}
+
+		if p.RoomCount != nil {
+			room.RoomCount = *p.RoomCount
+		}
+		if p.IsOccupied != nil {
+			room.IsOccupied = *p.IsOccupied
+		}
+		if p.Floor != nil {
+			room.Floor = *p.Floor
+		}
+		if p.SleepingPlaces != nil {
+			room.SleepingPlaces = *p.SleepingPlaces
+		}
+		if p.RoomType != nil {
+			room.RoomType = *p.RoomType
+		}
+		if p.NeedCleaning != nil {
+			room.NeedCleaning = *p.NeedCleaning
+		}
+
+		room.Version++
+		d.rooms[id] = room
+		newVersion = room.Version
+		return nil
+	})
+	return newVersion, err
+}
+
+// DeleteRoom also deletes the room's bookings, like ON DELETE CASCADE.
+func (r *MemoryRoomRepository) DeleteRoom(ctx context.Context, id, version int) error {
+	return r.tx.with(func(d *memoryData) error {
+		room, ok := d.rooms[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		if room.Version != version {
+			return ErrVersionMismatch
+		}
+		delete(d.rooms, id)
+		for bid, b := range d.bookings {
+			if b.RoomID == id {
+				delete(d.bookings, bid)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *MemoryRoomRepository) IsOccupied(ctx context.Context, roomID int) (bool, error) {
+	room, err := r.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	return room.IsOccupied, nil
+}
+
+// LockRoom only checks that the room exists: a unit of work already holds
+// the whole store.
+func (r *MemoryRoomRepository) LockRoom(ctx context.Context, id int) error {
+	_, err := r.GetRoomByID(ctx, id)
+	return err
+}
+
+type MemoryBookingRepository struct {
+	tx memoryTx
+}
+
+func (r *MemoryBookingRepository) CreateBooking(ctx context.Context, b md.Booking) (md.Booking, error) {
+	var created md.Booking
+	err := r.tx.with(func(d *memoryData) error {
+		b.ID = 0
+		checked, err := d.checkBooking(b)
+		if err != nil {
+			return err
+		}
+		d.lastBookingID++
+		checked.ID = d.lastBookingID
+		checked.Version = 1
+		d.bookings[checked.ID] = checked
+		created = checked
+		return nil
+	})
+	return created, err
+}
+
+func (r *MemoryBookingRepository) GettingStatus(ctx context.Context, guest_id int) (bool, error) {
+	var checkedIn bool
+	err := r.tx.with(func(d *memoryData) error {
+		for _, b := range d.bookings {
+			if b.GuestID == guest_id && b.Status == "true" {
+				checkedIn = true
+				break
+			}
+		}
+		return nil
+	})
+	return checkedIn, err
+}
+
+func (r *MemoryBookingRepository) ArrivalStatusOfRoom(ctx context.Context, RoomID int) (bool, error) {
+	var checkedIn bool
+	err := r.tx.with(func(d *memoryData) error {
+		for _, b := range d.bookings {
+			if b.RoomID == RoomID && b.Status == "true" {
+				checkedIn = true
+				break
+			}
+		}
+		return nil
+	})
+	return checkedIn, err
+}
+
+func (r *MemoryBookingRepository) HasOverlap(ctx context.Context, roomID int, start, end time.Time, excludeID int) (bool, error) {
+	var overlaps bool
+	err := r.tx.with(func(d *memoryData) error {
+		overlaps = d.hasOverlap(roomID, start, end, excludeID)
+		return nil
+	})
+	return overlaps, err
+}
+
+func (r *MemoryBookingRepository) ReadBookingByID(ctx context.Context, id int) (md.Booking, error) {
+	var b md.Booking
+	err := r.tx.with(func(d *memoryData) error {
+		var ok bool
+		if b, ok = d.bookings[id]; !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	return b, err
+}
+
+func (r *MemoryBookingRepository) PatchBooking(ctx context.Context, version int, p dto.BookingPatch) (int, error) {
+	if p.ID == nil {
+		return 0, errors.New("booking id is required")
+	}
+
+	var newVersion int
+	err := r.tx.with(func(d *memoryData) error {
+		b, ok := d.bookings[*p.ID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		if b.Version != version {
+			return ErrVersionMismatch
+		}
+
+		if p.RoomID != nil {
+			b.RoomID = *p.RoomID
+		}
+		if p.GuestID != nil {
+			b.GuestID = *p.GuestID
+		}
+		if p.Start_date != nil {
+			b.Start_date = *p.Start_date
+		}
+		if p.End_date != nil {
+			b.End_date = *p.End_date
+		}
+		if p.Status != nil {
+			b.Status = *p.Status
+		}
+
+		checked, err := d.checkBooking(b)
+		if err != nil {
+			return err
+		}
+		checked.Version++
+		d.bookings[checked.ID] = checked
+		newVersion = checked.Version
+		return nil
+	})
+	return newVersion, err
+}
+
+func (r *MemoryBookingRepository) ListColumn(ctx context.Context) ([]md.Booking, error) {
+	var bookings []md.Booking
+	err := r.tx.with(func(d *memoryData) error {
+		for _, b := range d.bookings {
+			bookings = append(bookings, b)
+		}
+		return nil
+	})
+	sort.Slice(bookings, func(i, j int) bool { return bookings[i].ID < bookings[j].ID })
+	return bookings, err
+}
+
+func (r *MemoryBookingRepository) FilterBookings(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	var responses map[string][]int
+	err := r.tx.with(func(d *memoryData) error {
+		var err error
+		responses, err = filterIDs(d.bookings, filter, bookingColumns, func(_ string, value any) string { return fmt.Sprintf("%v", value) })
+		return err
+	})
+	return responses, err
+}
+
+func bookingColumns(b md.Booking) map[string]any {
+	return map[string]any{
+		"id":         b.ID,
+		"room_id":    b.RoomID,
+		"guest_id":   b.GuestID,
+		"start_date": b.Start_date,
+		"end_date":   b.End_date,
+		"status":     b.Status == "true",
+		"version":    b.Version,
+	}
+}
+
+func (r *MemoryBookingRepository) DeleteBooking(ctx context.Context, id, version int) error {
+	return r.tx.with(func(d *memoryData) error {
+		b, ok := d.bookings[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		if b.Version != version {
+			return ErrVersionMismatch
+		}
+		delete(d.bookings, id)
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	md "golangHotelProject/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The shared behavior is covered by contract_test.go; these are the details
+// the memory store has to emulate on its own.
+
+func TestMemoryBookingRepository_StoresDatesAndStatusLikeTheDatabase(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	room, err := store.Rooms().CreateRoom(ctx, md.Room{Number: 101, RoomCount: 1, Floor: 1, SleepingPlaces: 2, RoomType: "Standard"})
+	require.NoError(t, err)
+	afternoon := time.Date(2031, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	b, err := store.Bookings().CreateBooking(ctx, md.Booking{RoomID: room.ID, GuestID: 1, Start_date: afternoon, End_date: afternoon.AddDate(0, 0, 2), Status: "t"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "true", b.Status)
+	assert.Equal(t, time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC), b.Start_date)
+
+	_, err = store.Bookings().CreateBooking(ctx, md.Booking{RoomID: room.ID, GuestID: 2, Start_date: afternoon.AddDate(0, 0, 5), End_date: afternoon.AddDate(0, 0, 6), Status: "maybe"})
+	assert.Error(t, err)
+}
+
+func TestMemoryRoomRepository_RejectsUnknownRoomType(t *testing.T) {
+	_, err := NewMemoryStore().Rooms().CreateRoom(context.Background(), md.Room{Number: 101, RoomType: "Penthouse"})
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrConflict)
+}
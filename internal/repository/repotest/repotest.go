@@ -0,0 +1,451 @@
+// Package repotest is the behavior every RoomRepository, BookingRepository
+// and UnitOfWork implementation has to share. A backend's test calls Run
+// with a factory for fresh, empty storage.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golangHotelProject/internal/delivery/handlers/dto"
+	md "golangHotelProject/internal/model"
+	"golangHotelProject/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Backend is one empty storage: repositories working outside a transaction
+// and a unit of work over the same data.
+type Backend struct {
+	Rooms    repository.RoomRepository
+	Bookings repository.BookingRepository
+	UoW      repository.UnitOfWork
+}
+
+// Factory returns a new empty Backend. It is called once per test case.
+type Factory func(t *testing.T) Backend
+
+// Run runs the whole contract against the backend made by newBackend.
+func Run(t *testing.T, newBackend Factory) {
+	t.Run("Rooms", func(t *testing.T) { RunRooms(t, newBackend) })
+	t.Run("Bookings", func(t *testing.T) { RunBookings(t, newBackend) })
+	t.Run("UnitOfWork", func(t *testing.T) { RunUnitOfWork(t, newBackend) })
+}
+
+var day = time.Date(2031, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func newRoom(number int) md.Room {
+	return md.Room{Number: number, RoomCount: 2, Floor: number / 100, SleepingPlaces: 3, RoomType: "Deluxe"}
+}
+
+func mustCreateRoom(t *testing.T, b Backend, number int) md.Room {
+	t.Helper()
+	room, err := b.Rooms.CreateRoom(context.Background(), newRoom(number))
+	require.NoError(t, err)
+	return room
+}
+
+func newBooking(roomID, guestID, fromDay, nights int) md.Booking {
+	start := day.AddDate(0, 0, fromDay)
+	return md.Booking{RoomID: roomID, GuestID: guestID, Start_date: start, End_date: start.AddDate(0, 0, nights), Status: "false"}
+}
+
+func mustCreateBooking(t *testing.T, b Backend, booking md.Booking) md.Booking {
+	t.Helper()
+	created, err := b.Bookings.CreateBooking(context.Background(), booking)
+	require.NoError(t, err)
+	return created
+}
+
+// fullPatch is the patch the booking usecase sends: every column set.
+func fullPatch(b md.Booking) dto.BookingPatch {
+	return dto.BookingPatch{
+		ID:         &b.ID,
+		RoomID:     &b.RoomID,
+		GuestID:    &b.GuestID,
+		Start_date: &b.Start_date,
+		End_date:   &b.End_date,
+		Status:     &b.Status,
+	}
+}
+
+func RunRooms(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndRead", func(t *testing.T) {
+		b := newBackend(t)
+		want := newRoom(301)
+
+		created, err := b.Rooms.CreateRoom(ctx, want)
+		require.NoError(t, err)
+		assert.Positive(t, created.ID)
+		assert.Equal(t, 1, created.Version)
+		want.ID, want.Version = created.ID, created.Version
+		assert.Equal(t, want, created)
+
+		got, err := b.Rooms.GetRoomByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+
+		list, err := b.Rooms.ListRoom(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []md.Room{created}, list)
+
+		occupied, err := b.Rooms.IsOccupied(ctx, created.ID)
+		require.NoError(t, err)
+		assert.False(t, occupied)
+		assert.NoError(t, b.Rooms.LockRoom(ctx, created.ID))
+	})
+
+	t.Run("NumbersAreUnique", func(t *testing.T) {
+		b := newBackend(t)
+		mustCreateRoom(t, b, 301)
+
+		_, err := b.Rooms.CreateRoom(ctx, newRoom(301))
+		assert.ErrorIs(t, err, repository.ErrConflict)
+
+		exists, err := b.Rooms.IsNumberExists(ctx, 301)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		exists, err = b.Rooms.IsNumberExists(ctx, 302)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		b := newBackend(t)
+		const missing = 424242
+		floor := 1
+
+		_, err := b.Rooms.GetRoomByID(ctx, missing)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = b.Rooms.IsOccupied(ctx, missing)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, b.Rooms.LockRoom(ctx, missing), sql.ErrNoRows)
+		_, err = b.Rooms.PatchRoom(ctx, missing, 1, dto.RoomPatch{Floor: &floor})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, b.Rooms.DeleteRoom(ctx, missing, 1), sql.ErrNoRows)
+	})
+
+	t.Run("PatchBumpsVersion", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		floor, cleaning := 7, true
+
+		version, err := b.Rooms.PatchRoom(ctx, room.ID, room.Version, dto.RoomPatch{Floor: &floor, NeedCleaning: &cleaning})
+		require.NoError(t, err)
+		assert.Equal(t, room.Version+1, version)
+
+		got, err := b.Rooms.GetRoomByID(ctx, room.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 7, got.Floor)
+		assert.True(t, got.NeedCleaning)
+		assert.Equal(t, room.RoomType, got.RoomType)
+		assert.Equal(t, version, got.Version)
+
+		_, err = b.Rooms.PatchRoom(ctx, room.ID, room.Version, dto.RoomPatch{Floor: &floor})
+		assert.ErrorIs(t, err, repository.ErrVersionMismatch)
+
+		same, err := b.Rooms.PatchRoom(ctx, room.ID, version, dto.RoomPatch{})
+		require.NoError(t, err)
+		assert.Equal(t, version, same, "an empty patch changes nothing")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+
+		assert.ErrorIs(t, b.Rooms.DeleteRoom(ctx, room.ID, room.Version+1), repository.ErrVersionMismatch)
+		require.NoError(t, b.Rooms.DeleteRoom(ctx, room.ID, room.Version))
+
+		_, err := b.Rooms.GetRoomByID(ctx, room.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		b := newBackend(t)
+		first := mustCreateRoom(t, b, 301)
+		second := mustCreateRoom(t, b, 302)
+		mustCreateRoom(t, b, 401)
+
+		// Values arrive as decoded JSON, so numbers are float64.
+		got, err := b.Rooms.FilterRoom(ctx, map[string]interface{}{"floor": float64(3)})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{first.ID, second.ID}, got["floor"])
+
+		got, err = b.Rooms.FilterRoom(ctx, map[string]interface{}{"floor": float64(9)})
+		require.NoError(t, err)
+		assert.Empty(t, got)
+
+		var unknown *repository.UnknownColumnError
+		_, err = b.Rooms.FilterRoom(ctx, map[string]interface{}{"no_such_column": 1})
+		assert.ErrorAs(t, err, &unknown)
+
+		// Filter keys come from request bodies and must never reach the SQL.
+		_, err = b.Rooms.FilterRoom(ctx, map[string]interface{}{"1 = 1 OR id": 1})
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "1 = 1 OR id", unknown.Column)
+	})
+}
+
+func RunBookings(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndRead", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+
+		created := mustCreateBooking(t, b, newBooking(room.ID, 7, 0, 3))
+		assert.Positive(t, created.ID)
+		assert.Equal(t, 1, created.Version)
+		assert.Equal(t, "false", created.Status)
+		assert.True(t, day.Equal(created.Start_date), "start_date: %v", created.Start_date)
+		assert.True(t, day.AddDate(0, 0, 3).Equal(created.End_date), "end_date: %v", created.End_date)
+
+		got, err := b.Bookings.ReadBookingByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, got.ID)
+		assert.Equal(t, created.RoomID, got.RoomID)
+		assert.Equal(t, created.GuestID, got.GuestID)
+		assert.Equal(t, created.Status, got.Status)
+		assert.Equal(t, created.Version, got.Version)
+
+		list, err := b.Bookings.ListColumn(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, created.ID, list[0].ID)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		b := newBackend(t)
+		const missing = 424242
+		room := mustCreateRoom(t, b, 301)
+		booking := newBooking(room.ID, 1, 0, 1)
+		booking.ID = missing
+
+		_, err := b.Bookings.ReadBookingByID(ctx, missing)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = b.Bookings.PatchBooking(ctx, 1, fullPatch(booking))
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, b.Bookings.DeleteBooking(ctx, missing, 1), sql.ErrNoRows)
+	})
+
+	t.Run("RoomMustExist", func(t *testing.T) {
+		b := newBackend(t)
+
+		_, err := b.Bookings.CreateBooking(ctx, newBooking(424242, 1, 0, 1))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, repository.ErrConflict)
+	})
+
+	t.Run("DeletingRoomDeletesItsBookings", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		booking := mustCreateBooking(t, b, newBooking(room.ID, 1, 0, 2))
+
+		require.NoError(t, b.Rooms.DeleteRoom(ctx, room.ID, room.Version))
+
+		_, err := b.Bookings.ReadBookingByID(ctx, booking.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		other := mustCreateRoom(t, b, 302)
+		first := mustCreateBooking(t, b, newBooking(room.ID, 1, 0, 2))
+
+		_, err := b.Bookings.CreateBooking(ctx, newBooking(room.ID, 2, 1, 2))
+		assert.ErrorIs(t, err, repository.ErrConflict)
+
+		mustCreateBooking(t, b, newBooking(room.ID, 3, 2, 2))
+		mustCreateBooking(t, b, newBooking(other.ID, 4, 0, 2))
+
+		overlaps, err := b.Bookings.HasOverlap(ctx, room.ID, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), 0)
+		require.NoError(t, err)
+		assert.True(t, overlaps)
+		overlaps, err = b.Bookings.HasOverlap(ctx, room.ID, day, day.AddDate(0, 0, 2), first.ID)
+		require.NoError(t, err)
+		assert.False(t, overlaps, "the booking being edited does not overlap itself")
+		overlaps, err = b.Bookings.HasOverlap(ctx, room.ID, day.AddDate(0, 0, 4), day.AddDate(0, 0, 6), 0)
+		require.NoError(t, err)
+		assert.False(t, overlaps, "check-out day is free for the next guest")
+	})
+
+	t.Run("PatchIntoOverlapIsConflict", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		first := mustCreateBooking(t, b, newBooking(room.ID, 1, 0, 2))
+		second := mustCreateBooking(t, b, newBooking(room.ID, 2, 2, 2))
+
+		moved := second
+		moved.Start_date = day.AddDate(0, 0, 1)
+		_, err := b.Bookings.PatchBooking(ctx, second.Version, fullPatch(moved))
+		assert.ErrorIs(t, err, repository.ErrConflict)
+
+		untouched, err := b.Bookings.ReadBookingByID(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first.Version, untouched.Version)
+		untouched, err = b.Bookings.ReadBookingByID(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second.Version, untouched.Version)
+	})
+
+	t.Run("ConcurrentOverlapsAreRejected", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+
+		// Every request shares at least one night with every other.
+		const workers = 16
+		var created, conflicts atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := b.Bookings.CreateBooking(ctx, newBooking(room.ID, 1000+i, i%3, 3))
+				switch {
+				case err == nil:
+					created.Add(1)
+				case errors.Is(err, repository.ErrConflict):
+					conflicts.Add(1)
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), created.Load())
+		assert.Equal(t, int32(workers-1), conflicts.Load())
+	})
+
+	t.Run("PatchBumpsVersion", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		booking := mustCreateBooking(t, b, newBooking(room.ID, 1, 0, 2))
+
+		changed := booking
+		changed.Status = "true"
+		changed.End_date = day.AddDate(0, 0, 5)
+		version, err := b.Bookings.PatchBooking(ctx, booking.Version, fullPatch(changed))
+		require.NoError(t, err)
+		assert.Equal(t, booking.Version+1, version)
+
+		got, err := b.Bookings.ReadBookingByID(ctx, booking.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "true", got.Status)
+		assert.True(t, day.AddDate(0, 0, 5).Equal(got.End_date))
+
+		_, err = b.Bookings.PatchBooking(ctx, booking.Version, fullPatch(changed))
+		assert.ErrorIs(t, err, repository.ErrVersionMismatch)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		booking := mustCreateBooking(t, b, newBooking(room.ID, 1, 0, 2))
+
+		assert.ErrorIs(t, b.Bookings.DeleteBooking(ctx, booking.ID, booking.Version+1), repository.ErrVersionMismatch)
+		require.NoError(t, b.Bookings.DeleteBooking(ctx, booking.ID, booking.Version))
+
+		_, err := b.Bookings.ReadBookingByID(ctx, booking.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("CheckedInStatus", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		other := mustCreateRoom(t, b, 302)
+		checkedIn := newBooking(room.ID, 1, 0, 2)
+		checkedIn.Status = "true"
+		mustCreateBooking(t, b, checkedIn)
+		mustCreateBooking(t, b, newBooking(other.ID, 2, 0, 2))
+
+		for _, tc := range []struct {
+			name string
+			fn   func(context.Context, int) (bool, error)
+			id   int
+			want bool
+		}{
+			{"guest checked in", b.Bookings.GettingStatus, 1, true},
+			{"guest not checked in", b.Bookings.GettingStatus, 2, false},
+			{"unknown guest", b.Bookings.GettingStatus, 3, false},
+			{"room has a guest", b.Bookings.ArrivalStatusOfRoom, room.ID, true},
+			{"room has no guest", b.Bookings.ArrivalStatusOfRoom, other.ID, false},
+		} {
+			got, err := tc.fn(ctx, tc.id)
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.want, got, tc.name)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		b := newBackend(t)
+		room := mustCreateRoom(t, b, 301)
+		first := mustCreateBooking(t, b, newBooking(room.ID, 42, 0, 2))
+		second := mustCreateBooking(t, b, newBooking(room.ID, 42, 4, 2))
+		mustCreateBooking(t, b, newBooking(room.ID, 43, 8, 2))
+
+		// Bookings are keyed by the filter value, not the column.
+		got, err := b.Bookings.FilterBookings(ctx, map[string]interface{}{"guest_id": float64(42)})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{first.ID, second.ID}, got["42"])
+
+		var unknown *repository.UnknownColumnError
+		_, err = b.Bookings.FilterBookings(ctx, map[string]interface{}{"no_such_column": 1})
+		assert.ErrorAs(t, err, &unknown)
+
+		_, err = b.Bookings.FilterBookings(ctx, map[string]interface{}{"guest_id = guest_id OR id": 1})
+		assert.ErrorAs(t, err, &unknown)
+	})
+}
+
+func RunUnitOfWork(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("CommitsOnSuccess", func(t *testing.T) {
+		b := newBackend(t)
+
+		var room md.Room
+		err := b.UoW.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			var err error
+			if room, err = repos.Rooms.CreateRoom(ctx, newRoom(301)); err != nil {
+				return err
+			}
+			if err := repos.Rooms.LockRoom(ctx, room.ID); err != nil {
+				return err
+			}
+			_, err = repos.Bookings.CreateBooking(ctx, newBooking(room.ID, 1, 0, 2))
+			return err
+		})
+		require.NoError(t, err)
+
+		bookings, err := b.Bookings.ListColumn(ctx)
+		require.NoError(t, err)
+		assert.Len(t, bookings, 1)
+	})
+
+	t.Run("RollsBackOnError", func(t *testing.T) {
+		b := newBackend(t)
+		boom := errors.New("boom")
+
+		err := b.UoW.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			if _, err := repos.Rooms.CreateRoom(ctx, newRoom(301)); err != nil {
+				return err
+			}
+			return boom
+		})
+		assert.ErrorIs(t, err, boom)
+
+		exists, err := b.Rooms.IsNumberExists(ctx, 301)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
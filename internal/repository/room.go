@@ -6,32 +6,41 @@ import (
 	"errors"
 	"fmt"
 	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/logger"
 	md "golangHotelProject/internal/model"
 	"golangHotelProject/internal/repository/db"
-	"log"
 	"strconv"
 	"strings"
 )
 
 type RoomRepository interface {
-	CreateRoom(ctx context.Context, room md.Room) error
+	CreateRoom(ctx context.Context, room md.Room) (md.Room, error)
 	ListRoom(ctx context.Context) ([]md.Room, error)
+	GetRoomByID(ctx context.Context, id int) (md.Room, error)
 	FilterRoom(ctx context.Context, filter map[string]interface{}) (map[string][]int, error)
 	IsNumberExists(ctx context.Context, number int) (bool, error)
-	PatchRoom(ctx context.Context, id int, p dto.RoomPatch) error
-	DeleteRoom(ctx context.Context, id int) error
+	PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (int, error)
+	DeleteRoom(ctx context.Context, id, version int) error
 	IsOccupied(ctx context.Context, roomID int) (bool, error)
+	LockRoom(ctx context.Context, id int) error
 }
 
 type PgRoomRepository struct {
-	DB *sql.DB
+	DB db.DBTX
 }
 
-func (r *PgRoomRepository) CreateRoom(ctx context.Context, room md.Room) error {
-	_, err := r.DB.ExecContext(ctx, `INSERT INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
-	VALUES($1, $2, $3, $4, $5, $6, $7)`, room.Number, room.RoomCount, room.IsOccupied, room.Floor, room.SleepingPlaces, room.RoomType, room.NeedCleaning)
+func (r *PgRoomRepository) CreateRoom(ctx context.Context, room md.Room) (md.Room, error) {
+	const q = `INSERT INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
+	VALUES($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version`
 
-	return err
+	var created md.Room
+	err := r.DB.QueryRowContext(ctx, q, room.Number, room.RoomCount, room.IsOccupied, room.Floor, room.SleepingPlaces, room.RoomType, room.NeedCleaning).
+		Scan(&created.ID, &created.Number, &created.RoomCount, &created.IsOccupied, &created.Floor, &created.SleepingPlaces, &created.RoomType, &created.NeedCleaning, &created.Version)
+	if err != nil {
+		return md.Room{}, translatePgError(err)
+	}
+	return created, nil
 }
 
 func (r *PgRoomRepository) IsNumberExists(ctx context.Context, number int) (bool, error) {
@@ -50,14 +59,14 @@ func (r *PgRoomRepository) IsNumberExists(ctx context.Context, number int) (bool
 
 func (r *PgRoomRepository) ListRoom(ctx context.Context) ([]md.Room, error) {
 
-	rows, err := r.DB.QueryContext(ctx, `SELECT id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning FROM rooms`)
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version FROM rooms`)
 	if err != nil {
 		return nil, err
 	}
 
 	defer func() {
 		if err := rows.Close(); err != nil {
-			log.Printf("error closing rows: %v", err)
+			logger.FromContext(ctx).Error("error closing rows", "error", err)
 		}
 	}()
 
@@ -66,7 +75,7 @@ func (r *PgRoomRepository) ListRoom(ctx context.Context) ([]md.Room, error) {
 	for rows.Next() {
 		var r md.Room
 
-		err := rows.Scan(&r.ID, &r.Number, &r.RoomCount, &r.IsOccupied, &r.Floor, &r.SleepingPlaces, &r.RoomType, &r.NeedCleaning)
+		err := rows.Scan(&r.ID, &r.Number, &r.RoomCount, &r.IsOccupied, &r.Floor, &r.SleepingPlaces, &r.RoomType, &r.NeedCleaning, &r.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -82,34 +91,63 @@ func (r *PgRoomRepository) ListRoom(ctx context.Context) ([]md.Room, error) {
 	return rooms, nil
 }
 
+func (r *PgRoomRepository) GetRoomByID(ctx context.Context, id int) (md.Room, error) {
+	const q = `SELECT id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version FROM rooms WHERE id = $1`
+
+	var room md.Room
+	err := r.DB.QueryRowContext(ctx, q, id).Scan(&room.ID, &room.Number, &room.RoomCount, &room.IsOccupied, &room.Floor, &room.SleepingPlaces, &room.RoomType, &room.NeedCleaning, &room.Version)
+	if err != nil {
+		return md.Room{}, err
+	}
+	return room, nil
+}
+
 func (r *PgRoomRepository) FilterRoom(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	if err := checkFilterColumns(filter, roomColumns(md.Room{})); err != nil {
+		return nil, err
+	}
 	responses := make(map[string][]int)
 	for column, value := range filter {
-		query := fmt.Sprintf("SELECT id FROM rooms WHERE %s = $1", column)
-		rows, err := db.DB.QueryContext(ctx, query, value)
+		ids, err := filterIDsByColumn(ctx, r.DB, "rooms", column, value)
 		if err != nil {
 			return nil, err
 		}
+		if len(ids) > 0 {
+			responses[column] = append(responses[column], ids...)
+		}
+	}
+	return responses, nil
+}
 
-		defer func() {
-			if err := rows.Close(); err != nil {
-				log.Printf("error closing rows: %v", err)
-			}
-		}()
+// filterIDsByColumn returns the ids of rows in table whose column equals value.
+// column is put into the query as is; callers check it with
+// checkFilterColumns first. The rows are closed before it returns, so a
+// filter on several columns holds one result set open at a time.
+func filterIDsByColumn(ctx context.Context, conn db.DBTX, table, column string, value any) ([]int, error) {
+	query := fmt.Sprintf("SELECT id FROM %s WHERE %s = $1 ORDER BY id", table, column)
+	rows, err := conn.QueryContext(ctx, query, value)
+	if err != nil {
+		return nil, err
+	}
 
-		for rows.Next() {
-			var currentID int
-			if err := rows.Scan(&currentID); err != nil {
-				return nil, err
-			}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("error closing rows", "error", err)
+		}
+	}()
 
-			strColumn := fmt.Sprintf("%v", column)
-			responses[strColumn] = append(responses[strColumn], currentID)
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
 	}
-	return responses, nil
+	return ids, rows.Err()
 }
-func (r *PgRoomRepository) PatchRoom(ctx context.Context, id int, p dto.RoomPatch) error {
+
+func (r *PgRoomRepository) PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (int, error) {
 	sets := make([]string, 0, 7)
 	args := make([]any, 0, 7)
 
@@ -141,13 +179,27 @@ func (r *PgRoomRepository) PatchRoom(ctx context.Context, id int, p dto.RoomPatc
 	}
 
 	if len(sets) == 0 {
-		return nil
+		return version, nil
 	}
 
+	sets = append(sets, "version = version + 1")
 	args = append(args, id)
-	q := "UPDATE rooms SET " + strings.Join(sets, ", ") + " WHERE id = $" + strconv.Itoa(len(args))
+	idArg := "$" + strconv.Itoa(len(args))
+	args = append(args, version)
+	q := "UPDATE rooms SET " + strings.Join(sets, ", ") + " WHERE id = " + idArg + " AND version = $" + strconv.Itoa(len(args)) + " RETURNING version"
 
-	res, err := r.DB.ExecContext(ctx, q, args...)
+	var newVersion int
+	if err := r.DB.QueryRowContext(ctx, q, args...).Scan(&newVersion); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, r.missOrStale(ctx, id)
+		}
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+func (r *PgRoomRepository) DeleteRoom(ctx context.Context, id, version int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1 AND version = $2`, id, version)
 	if err != nil {
 		return err
 	}
@@ -156,17 +208,20 @@ func (r *PgRoomRepository) PatchRoom(ctx context.Context, id int, p dto.RoomPatc
 		return err
 	}
 	if n == 0 {
-		return sql.ErrNoRows
+		return r.missOrStale(ctx, id)
 	}
 	return nil
 }
 
-func (r *PgRoomRepository) DeleteRoom(ctx context.Context, id int) error {
-	_, err := db.DB.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1`, id)
+// missOrStale tells apart the two reasons a conditional write can touch no
+// rows: the room is gone, or somebody else bumped its version first.
+func (r *PgRoomRepository) missOrStale(ctx context.Context, id int) error {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM rooms WHERE id = $1`, id).Scan(&one)
 	if err != nil {
 		return err
 	}
-	return nil
+	return ErrVersionMismatch
 }
 
 func (r *PgRoomRepository) IsOccupied(ctx context.Context, roomID int) (bool, error) {
@@ -180,3 +235,11 @@ func (r *PgRoomRepository) IsOccupied(ctx context.Context, roomID int) (bool, er
 	}
 	return occupied, nil
 }
+
+// LockRoom takes a row lock on the room for the rest of the transaction, so
+// concurrent bookings for the same room are checked one after another. Outside
+// a transaction the lock is released immediately.
+func (r *PgRoomRepository) LockRoom(ctx context.Context, id int) error {
+	var locked int
+	return r.DB.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/logger"
+	"golangHotelProject/internal/model"
+	"golangHotelProject/internal/repository/db"
+	"strconv"
+	"time"
+)
+
+const sqliteDateLayout = "2006-01-02"
+
+type SQLiteBookingRepository struct {
+	DB db.DBTX
+}
+
+// sqliteDate is how dates are stored: as YYYY-MM-DD text, so that comparing
+// the strings compares the dates.
+func sqliteDate(t time.Time) string {
+	return t.Format(sqliteDateLayout)
+}
+
+// sqliteStatus turns the API's string status into the stored BOOLEAN.
+func sqliteStatus(s string) (bool, error) {
+	status, err := normalizeStatus(s)
+	if err != nil {
+		return false, err
+	}
+	return status == "true", nil
+}
+
+const sqliteBookingColumns = `id, room_id, guest_id, start_date, end_date, status, version`
+
+func scanSQLiteBooking(row interface{ Scan(dest ...any) error }) (model.Booking, error) {
+	var b model.Booking
+	var status bool
+	if err := row.Scan(&b.ID, &b.RoomID, &b.GuestID, &b.Start_date, &b.End_date, &status, &b.Version); err != nil {
+		return model.Booking{}, err
+	}
+	b.Status = strconv.FormatBool(status)
+	return b, nil
+}
+
+func (r *SQLiteBookingRepository) CreateBooking(ctx context.Context, b model.Booking) (model.Booking, error) {
+	status, err := sqliteStatus(b.Status)
+	if err != nil {
+		return model.Booking{}, err
+	}
+
+	const q = `INSERT INTO bookings (room_id, guest_id, start_date, end_date, status)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING ` + sqliteBookingColumns
+
+	created, err := scanSQLiteBooking(r.DB.QueryRowContext(ctx, q, b.RoomID, b.GuestID, sqliteDate(b.Start_date), sqliteDate(b.End_date), status))
+	if err != nil {
+		return model.Booking{}, translateSQLiteError(err)
+	}
+	return created, nil
+}
+
+func (r *SQLiteBookingRepository) GettingStatus(ctx context.Context, guest_id int) (bool, error) {
+	var checkedIn bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bookings WHERE guest_id = $1 AND status)`, guest_id).Scan(&checkedIn)
+	return checkedIn, err
+}
+
+func (r *SQLiteBookingRepository) ArrivalStatusOfRoom(ctx context.Context, RoomID int) (bool, error) {
+	var checkedIn bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bookings WHERE room_id = $1 AND status)`, RoomID).Scan(&checkedIn)
+	return checkedIn, err
+}
+
+// HasOverlap reports whether another booking of the room shares at least one
+// night with [start, end). excludeID skips the booking being edited.
+func (r *SQLiteBookingRepository) HasOverlap(ctx context.Context, roomID int, start, end time.Time, excludeID int) (bool, error) {
+	const q = `SELECT EXISTS (
+		SELECT 1 FROM bookings
+		WHERE room_id = $1 AND id <> $4 AND start_date < $3 AND $2 < end_date
+	)`
+
+	var overlaps bool
+	if err := r.DB.QueryRowContext(ctx, q, roomID, sqliteDate(start), sqliteDate(end), excludeID).Scan(&overlaps); err != nil {
+		return false, err
+	}
+	return overlaps, nil
+}
+
+func (r *SQLiteBookingRepository) ReadBookingByID(ctx context.Context, id int) (model.Booking, error) {
+	return scanSQLiteBooking(r.DB.QueryRowContext(ctx, `SELECT `+sqliteBookingColumns+` FROM bookings WHERE id = $1`, id))
+}
+
+func (r *SQLiteBookingRepository) PatchBooking(ctx context.Context, version int, b dto.BookingPatch) (int, error) {
+	if b.ID == nil || b.RoomID == nil || b.GuestID == nil || b.Start_date == nil || b.End_date == nil || b.Status == nil {
+		return 0, errors.New("booking patch must carry every column")
+	}
+	status, err := sqliteStatus(*b.Status)
+	if err != nil {
+		return 0, err
+	}
+
+	const q = `UPDATE bookings SET room_id = $1, guest_id = $2, start_date = $3, end_date = $4, status = $5, version = version + 1
+	WHERE id = $6 AND version = $7 RETURNING version`
+
+	var newVersion int
+	err = r.DB.QueryRowContext(ctx, q, *b.RoomID, *b.GuestID, sqliteDate(*b.Start_date), sqliteDate(*b.End_date), status, *b.ID, version).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, sqliteMissOrStale(ctx, r.DB, "bookings", *b.ID)
+		}
+		return 0, translateSQLiteError(err)
+	}
+	return newVersion, nil
+}
+
+func (r *SQLiteBookingRepository) ListColumn(ctx context.Context) ([]model.Booking, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+sqliteBookingColumns+` FROM bookings ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("error closing rows", "error", err)
+		}
+	}()
+
+	var bookings []model.Booking
+	for rows.Next() {
+		b, err := scanSQLiteBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+	return bookings, rows.Err()
+}
+
+func (r *SQLiteBookingRepository) FilterBookings(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	if err := checkFilterColumns(filter, bookingColumns(model.Booking{})); err != nil {
+		return nil, err
+	}
+	responses := make(map[string][]int)
+	for column, value := range filter {
+		ids, err := filterIDsByColumn(ctx, r.DB, "bookings", column, value)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			key := fmt.Sprintf("%v", value)
+			responses[key] = append(responses[key], ids...)
+		}
+	}
+	return responses, nil
+}
+
+func (r *SQLiteBookingRepository) DeleteBooking(ctx context.Context, id, version int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM bookings WHERE id = $1 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sqliteMissOrStale(ctx, r.DB, "bookings", id)
+	}
+	return nil
+}
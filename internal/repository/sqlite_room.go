@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/logger"
+	md "golangHotelProject/internal/model"
+	"golangHotelProject/internal/repository/db"
+	"strconv"
+	"strings"
+)
+
+type SQLiteRoomRepository struct {
+	DB db.DBTX
+}
+
+func (r *SQLiteRoomRepository) CreateRoom(ctx context.Context, room md.Room) (md.Room, error) {
+	const q = `INSERT INTO rooms (number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning)
+	VALUES($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version`
+
+	var created md.Room
+	err := r.DB.QueryRowContext(ctx, q, room.Number, room.RoomCount, room.IsOccupied, room.Floor, room.SleepingPlaces, room.RoomType, room.NeedCleaning).
+		Scan(&created.ID, &created.Number, &created.RoomCount, &created.IsOccupied, &created.Floor, &created.SleepingPlaces, &created.RoomType, &created.NeedCleaning, &created.Version)
+	if err != nil {
+		return md.Room{}, translateSQLiteError(err)
+	}
+	return created, nil
+}
+
+func (r *SQLiteRoomRepository) IsNumberExists(ctx context.Context, number int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM rooms WHERE number = $1)`, number).Scan(&exists)
+	return exists, err
+}
+
+func (r *SQLiteRoomRepository) ListRoom(ctx context.Context) ([]md.Room, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version FROM rooms ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("error closing rows", "error", err)
+		}
+	}()
+
+	var rooms []md.Room
+	for rows.Next() {
+		var r md.Room
+		if err := rows.Scan(&r.ID, &r.Number, &r.RoomCount, &r.IsOccupied, &r.Floor, &r.SleepingPlaces, &r.RoomType, &r.NeedCleaning, &r.Version); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	return rooms, rows.Err()
+}
+
+func (r *SQLiteRoomRepository) GetRoomByID(ctx context.Context, id int) (md.Room, error) {
+	const q = `SELECT id, number, room_count, is_occupied, floor, sleeping_places, room_type, need_cleaning, version FROM rooms WHERE id = $1`
+
+	var room md.Room
+	err := r.DB.QueryRowContext(ctx, q, id).Scan(&room.ID, &room.Number, &room.RoomCount, &room.IsOccupied, &room.Floor, &room.SleepingPlaces, &room.RoomType, &room.NeedCleaning, &room.Version)
+	if err != nil {
+		return md.Room{}, err
+	}
+	return room, nil
+}
+
+func (r *SQLiteRoomRepository) FilterRoom(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+	if err := checkFilterColumns(filter, roomColumns(md.Room{})); err != nil {
+		return nil, err
+	}
+	responses := make(map[string][]int)
+	for column, value := range filter {
+		ids, err := filterIDsByColumn(ctx, r.DB, "rooms", column, value)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			responses[column] = append(responses[column], ids...)
+		}
+	}
+	return responses, nil
+}
+
+func (r *SQLiteRoomRepository) PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (int, error) {
+	sets := make([]string, 0, 7)
+	args := make([]any, 0, 7)
+
+	next := func() string { return "$" + strconv.Itoa(len(args)+1) }
+
+	if p.RoomCount != nil {
+		sets = append(sets, "room_count = "+next())
+		args = append(args, *p.RoomCount)
+	}
+	if p.IsOccupied != nil {
+		sets = append(sets, "is_occupied = "+next())
+		args = append(args, *p.IsOccupied)
+	}
+	if p.Floor != nil {
+		sets = append(sets, "floor = "+next())
+		args = append(args, *p.Floor)
+	}
+	if p.SleepingPlaces != nil {
+		sets = append(sets, "sleeping_places = "+next())
+		args = append(args, *p.SleepingPlaces)
+	}
+	if p.RoomType != nil {
+		sets = append(sets, "room_type = "+next())
+		args = append(args, *p.RoomType)
+	}
+	if p.NeedCleaning != nil {
+		sets = append(sets, "need_cleaning = "+next())
+		args = append(args, *p.NeedCleaning)
+	}
+
+	if len(sets) == 0 {
+		return version, nil
+	}
+
+	sets = append(sets, "version = version + 1")
+	args = append(args, id)
+	idArg := "$" + strconv.Itoa(len(args))
+	args = append(args, version)
+	q := "UPDATE rooms SET " + strings.Join(sets, ", ") + " WHERE id = " + idArg + " AND version = $" + strconv.Itoa(len(args)) + " RETURNING version"
+
+	var newVersion int
+	if err := r.DB.QueryRowContext(ctx, q, args...).Scan(&newVersion); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, sqliteMissOrStale(ctx, r.DB, "rooms", id)
+		}
+		return 0, translateSQLiteError(err)
+	}
+	return newVersion, nil
+}
+
+func (r *SQLiteRoomRepository) DeleteRoom(ctx context.Context, id, version int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sqliteMissOrStale(ctx, r.DB, "rooms", id)
+	}
+	return nil
+}
+
+// sqliteMissOrStale tells apart the two reasons a conditional write can touch
+// no rows: the row is gone, or somebody else bumped its version first.
+func sqliteMissOrStale(ctx context.Context, conn db.DBTX, table string, id int) error {
+	var one int
+	err := conn.QueryRowContext(ctx, `SELECT 1 FROM `+table+` WHERE id = $1`, id).Scan(&one)
+	if err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+func (r *SQLiteRoomRepository) IsOccupied(ctx context.Context, roomID int) (bool, error) {
+	var occupied bool
+	if err := r.DB.QueryRowContext(ctx, `SELECT is_occupied FROM rooms WHERE id = $1`, roomID).Scan(&occupied); err != nil {
+		return false, err
+	}
+	return occupied, nil
+}
+
+// LockRoom only checks that the room exists. SQLite has no row locks; a unit
+// of work holds the database write lock from its first statement instead.
+func (r *SQLiteRoomRepository) LockRoom(ctx context.Context, id int) error {
+	var one int
+	return r.DB.QueryRowContext(ctx, `SELECT 1 FROM rooms WHERE id = $1`, id).Scan(&one)
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"golangHotelProject/internal/repository/db"
+	"golangHotelProject/internal/repository/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteMigrations_DownAndSeed(t *testing.T) {
+	conn := dbtest.SQLite(t)
+	ctx := context.Background()
+
+	migrator, err := db.NewMigrator(conn, db.DriverSQLite)
+	require.NoError(t, err)
+	require.NoError(t, migrator.ApplySeed(ctx))
+	require.NoError(t, migrator.ApplySeed(ctx))
+
+	rooms, err := (&SQLiteRoomRepository{DB: conn}).ListRoom(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rooms, 3)
+	bookings, err := (&SQLiteBookingRepository{DB: conn}).ListColumn(ctx)
+	require.NoError(t, err)
+	assert.Len(t, bookings, 3)
+
+	n, err := migrator.Down(ctx, len(migrator.Migrations))
+	require.NoError(t, err)
+	assert.Equal(t, len(migrator.Migrations), n)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.False(t, s.Applied)
+	}
+}
+
+func TestSQLiteFilter_RejectsUnknownColumns(t *testing.T) {
+	conn := dbtest.SQLite(t)
+	ctx := context.Background()
+
+	// A filter key is spliced into the query, so anything that is not a
+	// known column has to be turned away before that.
+	injected := map[string]interface{}{"1 = 1 OR id": 1}
+
+	_, err := (&SQLiteRoomRepository{DB: conn}).FilterRoom(ctx, injected)
+	var unknown *UnknownColumnError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "1 = 1 OR id", unknown.Column)
+
+	_, err = (&SQLiteBookingRepository{DB: conn}).FilterBookings(ctx, injected)
+	assert.ErrorAs(t, err, &unknown)
+}
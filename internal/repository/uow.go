@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"golangHotelProject/internal/repository/db"
+)
+
+// Repositories is the set of repositories bound to one unit of work.
+type Repositories struct {
+	Rooms    RoomRepository
+	Bookings BookingRepository
+}
+
+// UnitOfWork runs fn against repositories that share one transaction. The
+// transaction commits when fn returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+type PgUnitOfWork struct {
+	DB *sql.DB
+}
+
+func (u *PgUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return runInTx(ctx, u.DB, func(tx *sql.Tx) Repositories {
+		return Repositories{
+			Rooms:    &PgRoomRepository{DB: db.Traced(tx, db.DriverPostgres)},
+			Bookings: &PgBookingRepository{DB: db.Traced(tx, db.DriverPostgres)},
+		}
+	}, fn)
+}
+
+// SQLiteUnitOfWork relies on the connection opening write transactions with
+// BEGIN IMMEDIATE (see db.OpenSQLite), which serializes units of work.
+type SQLiteUnitOfWork struct {
+	DB *sql.DB
+}
+
+func (u *SQLiteUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return runInTx(ctx, u.DB, func(tx *sql.Tx) Repositories {
+		return Repositories{
+			Rooms:    &SQLiteRoomRepository{DB: db.Traced(tx, db.DriverSQLite)},
+			Bookings: &SQLiteBookingRepository{DB: db.Traced(tx, db.DriverSQLite)},
+		}
+	}, fn)
+}
+
+func runInTx(ctx context.Context, conn *sql.DB, bind func(tx *sql.Tx) Repositories, fn func(ctx context.Context, repos Repositories) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx, bind(tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Bound returns a UnitOfWork whose Do runs fn on repos as they are. A usecase
+// built from repositories that already live inside a transaction gets one, so
+// that its own unit of work joins the outer transaction.
+func Bound(repos Repositories) UnitOfWork {
+	return boundUnitOfWork{repos: repos}
+}
+
+type boundUnitOfWork struct {
+	repos Repositories
+}
+
+func (u boundUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return fn(ctx, u.repos)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateOnly drops the time of day the way a DATE column does.
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// normalizeStatus accepts the spellings Postgres accepts for BOOLEAN input
+// and returns the value the way bookings are read back.
+func normalizeStatus(s string) (string, error) {
+	switch s {
+	case "t", "true", "TRUE", "True", "y", "yes", "on", "1":
+		return "true", nil
+	case "f", "false", "FALSE", "False", "n", "no", "off", "0", "":
+		return "false", nil
+	}
+	return "", fmt.Errorf("invalid input syntax for type boolean: %q", s)
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"golangHotelProject/internal/delivery/handlers/dto"
+	"golangHotelProject/internal/logger"
+	md "golangHotelProject/internal/model"
+	repo "golangHotelProject/internal/repository"
+	"log/slog"
+)
+
+const MaxBatchOperations = 100
+
+var ErrBatchAborted = errors.New("batch aborted")
+
+func IsBatchAbortedErr(err error) bool { return errors.Is(err, ErrBatchAborted) }
+
+// BatchResult is the outcome of one operation. In atomic mode every
+// operation other than the failing one reports ErrBatchAborted on failure.
+type BatchResult struct {
+	Index   int
+	Created bool
+	Value   any
+	Err     error
+}
+
+type BatchUsecase struct {
+	UoW    repo.UnitOfWork
+	Logger *slog.Logger
+	log    logger.Logger
+}
+
+func NewBatchUsecase(uow repo.UnitOfWork, log logger.Logger) *BatchUsecase {
+	return &BatchUsecase{
+		UoW:    uow,
+		Logger: log.With("component", "BatchUsecase"),
+		log:    log,
+	}
+}
+
+// Execute runs ops through the regular room and booking usecases so that
+// every item gets the same validation as the single-item endpoints. In atomic
+// mode all ops share one transaction; otherwise each op commits on its own.
+// The returned bool reports whether anything was committed.
+func (uc *BatchUsecase) Execute(ctx context.Context, ops []dto.BatchOperation, atomic bool) (_ []BatchResult, _ bool, err error) {
+	const op = "Execute"
+	ctx, end := startOp(ctx, "BatchUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
+
+	log.Debug("executing batch",
+		"op", op,
+		"operations", len(ops),
+		"atomic", atomic,
+	)
+
+	if len(ops) == 0 {
+		log.Warn("empty batch", "op", op)
+		return nil, false, errors.Join(ErrValidation, newError("empty_batch", "operations must not be empty"))
+	}
+	if len(ops) > MaxBatchOperations {
+		log.Warn("batch too large",
+			"op", op,
+			"operations", len(ops),
+		)
+		return nil, false, errors.Join(ErrValidation, newError("batch_too_large", "at most %d operations per batch", MaxBatchOperations))
+	}
+
+	if atomic {
+		return uc.executeAtomic(ctx, ops)
+	}
+
+	results := make([]BatchResult, len(ops))
+	failed := 0
+	for i, o := range ops {
+		var res BatchResult
+		err := uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+			res = uc.apply(ctx, repos, i, o)
+			return res.Err
+		})
+		if err != nil {
+			res.Err = err
+			failed++
+		}
+		results[i] = res
+	}
+
+	log.Info("batch executed",
+		"op", op,
+		"operations", len(ops),
+		"failed", failed,
+	)
+	return results, failed < len(ops), nil
+}
+
+func (uc *BatchUsecase) executeAtomic(ctx context.Context, ops []dto.BatchOperation) ([]BatchResult, bool, error) {
+	const op = "executeAtomic"
+	log := uc.logFor(ctx)
+
+	results := make([]BatchResult, len(ops))
+	failedAt := -1
+
+	err := uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		for i, o := range ops {
+			results[i] = uc.apply(ctx, repos, i, o)
+			if results[i].Err != nil {
+				failedAt = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		log.Info("atomic batch committed",
+			"op", op,
+			"operations", len(ops),
+		)
+		return results, true, nil
+	}
+
+	if failedAt < 0 {
+		log.Error("atomic batch transaction failed",
+			"op", op,
+			"error", err.Error(),
+		)
+		return nil, false, err
+	}
+
+	aborted := errors.Join(ErrBatchAborted, newError("batch_aborted", "rolled back because operation %d failed", failedAt))
+	for i := range results {
+		if i != failedAt {
+			results[i] = BatchResult{Index: i, Err: aborted}
+		}
+	}
+
+	log.Warn("atomic batch rolled back",
+		"op", op,
+		"failed_index", failedAt,
+		"error", results[failedAt].Err.Error(),
+	)
+	return results, false, nil
+}
+
+func (uc *BatchUsecase) apply(ctx context.Context, repos repo.Repositories, i int, o dto.BatchOperation) BatchResult {
+	res := BatchResult{Index: i}
+	rooms := NewRoomUsecase(repos.Rooms, uc.log)
+	bookings := NewBookingUsecase(repos.Bookings, repo.Bound(repos), uc.log)
+
+	switch o.Resource + "." + o.Op {
+	case "room.create":
+		var room md.Room
+		if res.Err = decodeBatchData(o.Data, &room); res.Err != nil {
+			return res
+		}
+		res.Value, res.Err = rooms.AddRoom(ctx, room)
+		res.Created = true
+	case "room.patch":
+		var patch dto.RoomPatch
+		if res.Err = decodeBatchData(o.Data, &patch); res.Err != nil {
+			return res
+		}
+		version, err := rooms.PatchRoom(ctx, o.ID, o.Version, patch)
+		res.Value, res.Err = dto.VersionedID{ID: o.ID, Version: version}, err
+	case "room.delete":
+		res.Err = rooms.RemoveRoom(ctx, o.ID, o.Version)
+		res.Value = dto.VersionedID{ID: o.ID}
+	case "booking.create":
+		var b md.Booking
+		if res.Err = decodeBatchData(o.Data, &b); res.Err != nil {
+			return res
+		}
+		res.Value, res.Err = bookings.CreateBooking(ctx, b)
+		res.Created = true
+	case "booking.patch":
+		var patch dto.BookingPatch
+		if res.Err = decodeBatchData(o.Data, &patch); res.Err != nil {
+			return res
+		}
+		id := o.ID
+		patch.ID = &id
+		version, err := bookings.PatchBookingByID(ctx, o.Version, patch)
+		res.Value, res.Err = dto.VersionedID{ID: o.ID, Version: version}, err
+	case "booking.delete":
+		res.Err = bookings.RemoveBooking(ctx, o.ID, o.Version)
+		res.Value = dto.VersionedID{ID: o.ID}
+	default:
+		res.Err = errors.Join(ErrValidation, newError("unsupported_operation", "unsupported operation %q on resource %q", o.Op, o.Resource))
+	}
+
+	if res.Err != nil {
+		res.Value = nil
+	}
+	return res
+}
+
+func decodeBatchData(data json.RawMessage, v any) error {
+	if len(data) == 0 {
+		return errors.Join(ErrValidation, newError("data_required", "data is required"))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errors.Join(ErrValidation, newError("invalid_data", "invalid data: %s", err.Error()))
+	}
+	return nil
+}
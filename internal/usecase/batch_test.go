@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"golangHotelProject/internal/delivery/handlers/dto"
+	md "golangHotelProject/internal/model"
+	repo "golangHotelProject/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeUnitOfWork hands the same mocks to every transaction and counts how
+// the transactions ended.
+type fakeUnitOfWork struct {
+	rooms     *MockRoomRepository
+	bookings  *MockBookingRepository
+	commits   int
+	rollbacks int
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repo.Repositories) error) error {
+	err := fn(ctx, repo.Repositories{Rooms: u.rooms, Bookings: u.bookings})
+	if err != nil {
+		u.rollbacks++
+		return err
+	}
+	u.commits++
+	return nil
+}
+
+func newFakeUnitOfWork() *fakeUnitOfWork {
+	return &fakeUnitOfWork{rooms: new(MockRoomRepository), bookings: new(MockBookingRepository)}
+}
+
+func roomCreateOp(number int) dto.BatchOperation {
+	data, _ := json.Marshal(md.Room{
+		Number:         number,
+		RoomCount:      1,
+		Floor:          1,
+		SleepingPlaces: 2,
+		RoomType:       "Standard",
+	})
+	return dto.BatchOperation{Op: "create", Resource: "room", Data: data}
+}
+
+func TestBatchExecute_AtomicCommitsAll(t *testing.T) {
+	uow := newFakeUnitOfWork()
+	uow.rooms.On("IsNumberExists", mock.Anything, mock.Anything).Return(false, nil)
+	uow.rooms.On("CreateRoom", mock.Anything, mock.Anything).Return(md.Room{ID: 1, Version: 1}, nil)
+
+	uc := NewBatchUsecase(uow, testLogger())
+	results, committed, err := uc.Execute(context.Background(), []dto.BatchOperation{roomCreateOp(101), roomCreateOp(102)}, true)
+
+	assert.NoError(t, err)
+	assert.True(t, committed)
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, results[1].Created)
+	assert.Equal(t, 1, uow.commits)
+}
+
+func TestBatchExecute_AtomicRollsBackOnFailure(t *testing.T) {
+	uow := newFakeUnitOfWork()
+	uow.rooms.On("IsNumberExists", mock.Anything, 101).Return(false, nil)
+	uow.rooms.On("IsNumberExists", mock.Anything, 102).Return(true, nil)
+	uow.rooms.On("CreateRoom", mock.Anything, mock.Anything).Return(md.Room{ID: 1, Version: 1}, nil)
+
+	uc := NewBatchUsecase(uow, testLogger())
+	ops := []dto.BatchOperation{roomCreateOp(101), roomCreateOp(102), roomCreateOp(103)}
+	results, committed, err := uc.Execute(context.Background(), ops, true)
+
+	assert.NoError(t, err)
+	assert.False(t, committed)
+	assert.Equal(t, 1, uow.rollbacks)
+	assert.True(t, IsBatchAbortedErr(results[0].Err))
+	assert.True(t, IsConflictErr(results[1].Err))
+	assert.True(t, IsBatchAbortedErr(results[2].Err))
+	assert.Nil(t, results[0].Value)
+}
+
+func TestBatchExecute_BestEffortKeepsGoing(t *testing.T) {
+	uow := newFakeUnitOfWork()
+	uow.rooms.On("IsNumberExists", mock.Anything, 101).Return(true, nil)
+	uow.rooms.On("IsNumberExists", mock.Anything, 102).Return(false, nil)
+	uow.rooms.On("CreateRoom", mock.Anything, mock.Anything).Return(md.Room{ID: 2, Version: 1}, nil)
+
+	uc := NewBatchUsecase(uow, testLogger())
+	results, committed, err := uc.Execute(context.Background(), []dto.BatchOperation{roomCreateOp(101), roomCreateOp(102)}, false)
+
+	assert.NoError(t, err)
+	assert.True(t, committed)
+	assert.True(t, IsConflictErr(results[0].Err))
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, md.Room{ID: 2, Version: 1}, results[1].Value)
+	assert.Equal(t, 1, uow.commits)
+	assert.Equal(t, 1, uow.rollbacks)
+}
+
+func TestBatchExecute_UnsupportedOperation(t *testing.T) {
+	uow := newFakeUnitOfWork()
+
+	uc := NewBatchUsecase(uow, testLogger())
+	results, _, err := uc.Execute(context.Background(), []dto.BatchOperation{{Op: "upsert", Resource: "room"}}, false)
+
+	assert.NoError(t, err)
+	assert.True(t, IsValidationErr(results[0].Err))
+}
+
+func TestBatchExecute_Empty(t *testing.T) {
+	uc := NewBatchUsecase(newFakeUnitOfWork(), testLogger())
+
+	_, _, err := uc.Execute(context.Background(), nil, true)
+
+	assert.True(t, IsValidationErr(err))
+}
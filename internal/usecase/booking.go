@@ -2,242 +2,369 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"golangHotelProject/internal/delivery/handlers/dto"
 	"golangHotelProject/internal/logger"
 	"golangHotelProject/internal/model"
 	repo "golangHotelProject/internal/repository"
+	"golangHotelProject/internal/validation"
 	"log/slog"
+	"time"
 )
 
+// BookingUsecase reads through Repo and runs every multi-step write through
+// UoW, so the checks and the writes that depend on them commit together.
 type BookingUsecase struct {
 	Repo   repo.BookingRepository
+	UoW    repo.UnitOfWork
 	Logger *slog.Logger
 }
 
-func NewBookingUsecase(repo repo.BookingRepository, log logger.Logger) *BookingUsecase {
+func NewBookingUsecase(repo repo.BookingRepository, uow repo.UnitOfWork, log logger.Logger) *BookingUsecase {
 	return &BookingUsecase{
 		Repo:   repo,
+		UoW:    uow,
 		Logger: log.With("component", "BookingUsecase"),
 	}
 }
 
-func (uc *BookingUsecase) CreateBooking(ctx context.Context, b model.Booking) error {
+func (uc *BookingUsecase) CreateBooking(ctx context.Context, b model.Booking) (_ model.Booking, err error) {
 	const op = "CreateBooking"
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("creating booking",
+	log.Debug("creating booking",
 		"op", op,
 		"room_id", b.RoomID,
 		"guest_id", b.GuestID,
 	)
 
-	err := validateBooking(b)
+	err = validateBooking(b)
 	if err != nil {
-		uc.Logger.Warn("booking validation failed",
+		log.Warn("booking validation failed",
 			"op", op,
 			"room_id", b.RoomID,
 			"guest_id", b.GuestID,
 			"error", err.Error(),
 		)
-		return errors.Join(ErrValidation, err)
+		return model.Booking{}, errors.Join(ErrValidation, err)
 	}
 
-	status, _ := uc.Repo.GettingStatus(ctx, b.GuestID)
-	if status {
-		uc.Logger.Warn("guest already has active booking",
-			"op", op,
-			"guest_id", b.GuestID,
-		)
-		return errors.Join(ErrValidation, errors.New("already have active booking with this guest_id"))
-	}
-
-	ArrivaledRoomBoolean, _ := uc.Repo.ArrivalStatusOfRoom(ctx, b.RoomID)
-	if ArrivaledRoomBoolean {
-		uc.Logger.Warn("room already booked",
-			"op", op,
-			"room_id", b.RoomID,
-		)
-		return errors.Join(ErrValidation, errors.New("already have active booking in this room_ID"))
-	}
-
-	if err := uc.Repo.CreateBooking(ctx, b); err != nil {
-		uc.Logger.Error("failed to create booking",
+	var created model.Booking
+	err = uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		if err := repos.Rooms.LockRoom(ctx, b.RoomID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Warn("room not found",
+					"op", op,
+					"room_id", b.RoomID,
+				)
+				return errors.Join(ErrValidation, newError("room_not_found", "room does not exist"))
+			}
+			return err
+		}
+
+		status, err := repos.Bookings.GettingStatus(ctx, b.GuestID)
+		if err != nil {
+			return err
+		}
+		if status {
+			log.Warn("guest already has active booking",
+				"op", op,
+				"guest_id", b.GuestID,
+			)
+			return errors.Join(ErrValidation, newError("guest_has_booking", "already have active booking with this guest_id"))
+		}
+
+		ArrivaledRoomBoolean, err := repos.Bookings.ArrivalStatusOfRoom(ctx, b.RoomID)
+		if err != nil {
+			return err
+		}
+		if ArrivaledRoomBoolean {
+			log.Warn("room already booked",
+				"op", op,
+				"room_id", b.RoomID,
+			)
+			return errors.Join(ErrValidation, newError("room_has_booking", "already have active booking in this room_ID"))
+		}
+
+		overlaps, err := repos.Bookings.HasOverlap(ctx, b.RoomID, b.Start_date, b.End_date, 0)
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			log.Warn("booking overlaps another booking",
+				"op", op,
+				"room_id", b.RoomID,
+			)
+			return errors.Join(ErrConflict, newError("room_booked", "room is already booked for these dates"))
+		}
+
+		created, err = repos.Bookings.CreateBooking(ctx, b)
+		return repoWriteErr(err)
+	})
+	if err != nil {
+		if IsValidationErr(err) || IsConflictErr(err) {
+			return model.Booking{}, err
+		}
+		log.Error("failed to create booking",
 			"op", op,
 			"room_id", b.RoomID,
 			"guest_id", b.GuestID,
 			"error", err.Error(),
 		)
-		return err
+		return model.Booking{}, err
 	}
 
-	uc.Logger.Info("booking created successfully",
+	log.Info("booking created successfully",
 		"op", op,
-		"room_id", b.RoomID,
-		"guest_id", b.GuestID,
+		"booking_id", created.ID,
+		"room_id", created.RoomID,
+		"guest_id", created.GuestID,
 	)
-	return nil
+	return created, nil
 }
 
-func validateBooking(b model.Booking) error {
-	if b.RoomID <= 0 {
-		return errors.Join(ErrValidation, errors.New("room id <= 0"))
-	}
-	if b.GuestID <= 0 {
-		return errors.Join(ErrValidation, errors.New("guest id <= 0"))
-	}
-	if b.Start_date.IsZero() || b.End_date.IsZero() {
-		return errors.New("some date is clear")
-	}
-	if !b.Start_date.Before(b.End_date) {
-		return errors.New("start_date должен быть раньше end_date")
+// bookingRules are the rules of the booking fields, shared by create and
+// patch.
+var bookingRules = struct {
+	RoomID, GuestID []validation.Rule[int]
+	Date            []validation.Rule[time.Time]
+}{
+	RoomID:  []validation.Rule[int]{validation.Min(1)},
+	GuestID: []validation.Rule[int]{validation.Min(1)},
+	Date:    []validation.Rule[time.Time]{validation.Required[time.Time]()},
+}
+
+// checkStay rejects a stay that does not end after it starts. Missing dates
+// are reported by the field rules already.
+func checkStay(v *validation.Validator, endField string, start, end time.Time) {
+	if !start.IsZero() && !end.IsZero() {
+		v.Check(start.Before(end), endField, "after_start", "must be after the start date")
 	}
-	return nil
 }
 
-func (uc *BookingUsecase) ReadByIDUsecase(ctx context.Context, id int) (model.Booking, error) {
+func validateBooking(b model.Booking) error {
+	var v validation.Validator
+	validation.Field(&v, "room_id", b.RoomID, bookingRules.RoomID...)
+	validation.Field(&v, "guest_id", b.GuestID, bookingRules.GuestID...)
+	validation.Field(&v, "start_date", b.Start_date, bookingRules.Date...)
+	validation.Field(&v, "end_date", b.End_date, bookingRules.Date...)
+	checkStay(&v, "end_date", b.Start_date, b.End_date)
+	return v.Err()
+}
+
+func (uc *BookingUsecase) ReadByIDUsecase(ctx context.Context, id int) (_ model.Booking, err error) {
 	const op = "ReadByIDUsecase"
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("reading booking by id",
+	log.Debug("reading booking by id",
 		"op", op,
 		"booking_id", id,
 	)
 
 	if id <= 0 {
-		uc.Logger.Warn("invalid booking id",
+		log.Warn("invalid booking id",
 			"op", op,
 			"booking_id", id,
 		)
-		return model.Booking{}, errors.Join(ErrValidation, errors.New("id <= 0"))
+		return model.Booking{}, errors.Join(ErrValidation, newError("invalid_id", "id <= 0"))
 	}
 
-	b, _ := uc.Repo.ReadBookingByID(ctx, id)
-	if b.RoomID == 0 && b.GuestID == 0 {
-		uc.Logger.Warn("booking not found",
+	b, err := uc.Repo.ReadBookingByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("booking not found",
+				"op", op,
+				"booking_id", id,
+			)
+			return model.Booking{}, errors.Join(ErrNotFound, newError("booking_not_found", "booking not found"))
+		}
+		log.Error("failed to read booking",
 			"op", op,
 			"booking_id", id,
+			"error", err.Error(),
 		)
-		return model.Booking{}, errors.Join(ErrValidation, errors.New("no rows"))
+		return model.Booking{}, err
 	}
 
-	uc.Logger.Debug("booking retrieved successfully",
+	log.Debug("booking retrieved successfully",
 		"op", op,
 		"booking_id", id,
 	)
 	return b, nil
 }
 
-func (uc *BookingUsecase) PatchBookingByID(ctx context.Context, b dto.BookingPatch) error {
+// PatchBookingByID merges b into the stored booking if it is still at
+// version and returns the booking's new version.
+func (uc *BookingUsecase) PatchBookingByID(ctx context.Context, version int, b dto.BookingPatch) (_ int, err error) {
 	const op = "PatchBookingByID"
-
-	uc.Logger.Debug("patching booking",
-		"op", op,
-		"booking_id", *b.ID,
-	)
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
 	if b.ID == nil || *b.ID <= 0 {
-		uc.Logger.Warn("invalid booking id",
+		log.Warn("invalid booking id",
 			"op", op,
 		)
-		return errors.Join(ErrValidation, errors.New("id <= 0"))
+		return 0, errors.Join(ErrValidation, newError("invalid_id", "id <= 0"))
 	}
 
-	old, _ := uc.Repo.ReadBookingByID(ctx, *b.ID)
-	if b.RoomID == nil {
-		b.RoomID = &old.RoomID
-	}
-	if b.GuestID == nil {
-		b.GuestID = &old.GuestID
-	}
-	if b.Start_date == nil {
-		b.Start_date = &old.Start_date
-	}
-	if b.End_date == nil {
-		b.End_date = &old.End_date
-	}
-	if b.Status == nil {
-		b.Status = &old.Status
-	}
+	log.Debug("patching booking",
+		"op", op,
+		"booking_id", *b.ID,
+		"version", version,
+	)
 
-	err := validateBookingPatch(b)
-	if err != nil {
-		uc.Logger.Warn("booking patch validation failed",
+	if version <= 0 {
+		log.Warn("invalid booking version",
 			"op", op,
 			"booking_id", *b.ID,
-			"error", err.Error(),
+			"version", version,
 		)
-		return errors.Join(ErrValidation, err)
+		return 0, errors.Join(ErrValidation, newError("invalid_version", "version must be more than 0"))
 	}
 
-	err = uc.Repo.PatchBooking(ctx, b)
+	var newVersion int
+	err = uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		old, err := repos.Bookings.ReadBookingByID(ctx, *b.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Join(ErrNotFound, newError("booking_not_found", "booking not found"))
+			}
+			return err
+		}
+		if b.RoomID == nil {
+			b.RoomID = &old.RoomID
+		}
+		if b.GuestID == nil {
+			b.GuestID = &old.GuestID
+		}
+		if b.Start_date == nil {
+			b.Start_date = &old.Start_date
+		}
+		if b.End_date == nil {
+			b.End_date = &old.End_date
+		}
+		if b.Status == nil {
+			b.Status = &old.Status
+		}
+
+		err = validateBookingPatch(b)
+		if err != nil {
+			log.Warn("booking patch validation failed",
+				"op", op,
+				"booking_id", *b.ID,
+				"error", err.Error(),
+			)
+			return errors.Join(ErrValidation, err)
+		}
+
+		if err := repos.Rooms.LockRoom(ctx, *b.RoomID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Join(ErrValidation, newError("room_not_found", "room does not exist"))
+			}
+			return err
+		}
+		overlaps, err := repos.Bookings.HasOverlap(ctx, *b.RoomID, *b.Start_date, *b.End_date, *b.ID)
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			log.Warn("booking overlaps another booking",
+				"op", op,
+				"booking_id", *b.ID,
+				"room_id", *b.RoomID,
+			)
+			return errors.Join(ErrConflict, newError("room_booked", "room is already booked for these dates"))
+		}
+
+		newVersion, err = repos.Bookings.PatchBooking(ctx, version, b)
+		if err != nil {
+			return errors.Join(errors.New("DB manipulating error"), repoWriteErr(err))
+		}
+		return nil
+	})
 	if err != nil {
-		uc.Logger.Error("failed to patch booking",
-			"op", op,
-			"booking_id", *b.ID,
-			"error", err.Error(),
-		)
-		return errors.Join(errors.New("DB manipulating error"), err)
+		if !IsValidationErr(err) && !IsConflictErr(err) && !IsNotFoundErr(err) {
+			log.Error("failed to patch booking",
+				"op", op,
+				"booking_id", *b.ID,
+				"version", version,
+				"error", err.Error(),
+			)
+		}
+		return 0, err
 	}
 
-	uc.Logger.Info("booking patched successfully",
+	log.Info("booking patched successfully",
 		"op", op,
 		"booking_id", *b.ID,
+		"version", newVersion,
 	)
-	return nil
+	return newVersion, nil
 }
 
+// validateBookingPatch checks a patch merged with the stored booking, so
+// every field is set. Fields are named as in the patch body.
 func validateBookingPatch(b dto.BookingPatch) error {
-	if b.RoomID == nil || *b.RoomID <= 0 {
-		return errors.Join(ErrValidation, errors.New("room id <= 0"))
-	}
-	if b.GuestID == nil || *b.GuestID <= 0 {
-		return errors.Join(ErrValidation, errors.New("guest id <= 0"))
-	}
-	if b.Start_date == nil || b.Start_date.IsZero() || b.End_date == nil || b.End_date.IsZero() {
-		return errors.New("some date is clear")
-	}
-	if !b.Start_date.Before(*b.End_date) {
-		return errors.New("start_date должен быть раньше end_date")
+	var v validation.Validator
+	validation.Optional(&v, "roomId", b.RoomID, bookingRules.RoomID...)
+	validation.Optional(&v, "guestId", b.GuestID, bookingRules.GuestID...)
+	validation.Optional(&v, "startDate", b.Start_date, bookingRules.Date...)
+	validation.Optional(&v, "endDate", b.End_date, bookingRules.Date...)
+	if b.Start_date != nil && b.End_date != nil {
+		checkStay(&v, "endDate", *b.Start_date, *b.End_date)
 	}
-	return nil
+	return v.Err()
 }
 
-func (uc *BookingUsecase) GetList(ctx context.Context) ([]model.Booking, error) {
+func (uc *BookingUsecase) GetList(ctx context.Context) (_ []model.Booking, err error) {
 	const op = "GetList"
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("fetching booking list", "op", op)
+	log.Debug("fetching booking list", "op", op)
 
 	response, _ := uc.Repo.ListColumn(ctx)
 	if len(response) == 0 {
-		uc.Logger.Info("booking list is empty", "op", op)
-		return response, errors.Join(ErrConflict, errors.New("database is clear"))
+		log.Info("booking list is empty", "op", op)
+		return response, errors.Join(ErrConflict, newError("no_bookings", "database is clear"))
 	}
 
-	uc.Logger.Debug("booking list fetched successfully",
+	log.Debug("booking list fetched successfully",
 		"op", op,
 		"count", len(response),
 	)
 	return response, nil
 }
 
-func (uc *BookingUsecase) GetFilteredBookings(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+func (uc *BookingUsecase) GetFilteredBookings(ctx context.Context, filter map[string]interface{}) (_ map[string][]int, err error) {
 	const op = "GetFilteredBookings"
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("filtering bookings",
+	log.Debug("filtering bookings",
 		"op", op,
 		"filter", filter,
 	)
 
 	response, err := uc.Repo.FilterBookings(ctx, filter)
 	if err != nil {
-		uc.Logger.Error("failed to filter bookings",
+		log.Error("failed to filter bookings",
 			"op", op,
 			"filter", filter,
 			"error", err.Error(),
 		)
-		return nil, err
+		return nil, filterErr(err)
 	}
 
-	uc.Logger.Debug("bookings filtered successfully",
+	log.Debug("bookings filtered successfully",
 		"op", op,
 		"filter", filter,
 		"results_count", len(response),
@@ -245,35 +372,175 @@ func (uc *BookingUsecase) GetFilteredBookings(ctx context.Context, filter map[st
 	return response, err
 }
 
-func (uc *BookingUsecase) RemoveBooking(ctx context.Context, id int) error {
+func (uc *BookingUsecase) RemoveBooking(ctx context.Context, id, version int) (err error) {
 	const op = "RemoveBooking"
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("removing booking",
+	log.Debug("removing booking",
 		"op", op,
 		"booking_id", id,
+		"version", version,
 	)
 
 	if id <= 0 {
-		uc.Logger.Warn("invalid booking id",
+		log.Warn("invalid booking id",
+			"op", op,
+			"booking_id", id,
+		)
+		return errors.Join(ErrValidation, newError("invalid_id", "ID must be more than 0"))
+	}
+	if version <= 0 {
+		log.Warn("invalid booking version",
 			"op", op,
 			"booking_id", id,
+			"version", version,
 		)
-		return errors.Join(ErrValidation, errors.New("ID must be more than 0"))
+		return errors.Join(ErrValidation, newError("invalid_version", "version must be more than 0"))
 	}
 
-	err := uc.Repo.DeleteBooking(ctx, id)
+	err = uc.Repo.DeleteBooking(ctx, id, version)
 	if err != nil {
-		uc.Logger.Error("failed to delete booking",
+		log.Error("failed to delete booking",
 			"op", op,
 			"booking_id", id,
+			"version", version,
 			"error", err.Error(),
 		)
-		return err
+		return repoWriteErr(err)
 	}
 
-	uc.Logger.Info("booking removed successfully",
+	log.Info("booking removed successfully",
 		"op", op,
 		"booking_id", id,
 	)
 	return nil
 }
+
+// CheckIn marks the booking as active and the room as occupied in one
+// transaction. version is the booking version the caller last saw.
+func (uc *BookingUsecase) CheckIn(ctx context.Context, id, version int) (model.Booking, error) {
+	return uc.changeStay(ctx, "CheckIn", id, version, true)
+}
+
+// CheckOut closes an active booking, frees the room and flags it for
+// cleaning in one transaction.
+func (uc *BookingUsecase) CheckOut(ctx context.Context, id, version int) (model.Booking, error) {
+	return uc.changeStay(ctx, "CheckOut", id, version, false)
+}
+
+func (uc *BookingUsecase) changeStay(ctx context.Context, op string, id, version int, checkIn bool) (_ model.Booking, err error) {
+	ctx, end := startOp(ctx, "BookingUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
+
+	log.Debug("changing stay",
+		"op", op,
+		"booking_id", id,
+		"version", version,
+	)
+
+	if id <= 0 {
+		log.Warn("invalid booking id",
+			"op", op,
+			"booking_id", id,
+		)
+		return model.Booking{}, errors.Join(ErrValidation, newError("invalid_id", "id <= 0"))
+	}
+	if version <= 0 {
+		log.Warn("invalid booking version",
+			"op", op,
+			"booking_id", id,
+			"version", version,
+		)
+		return model.Booking{}, errors.Join(ErrValidation, newError("invalid_version", "version must be more than 0"))
+	}
+
+	var updated model.Booking
+	err = uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		b, err := repos.Bookings.ReadBookingByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Join(ErrNotFound, newError("booking_not_found", "booking not found"))
+			}
+			return err
+		}
+
+		active := b.Status == "true"
+		if checkIn && active {
+			return errors.Join(ErrConflict, newError("already_checked_in", "booking is already checked in"))
+		}
+		if !checkIn && !active {
+			return errors.Join(ErrConflict, newError("not_checked_in", "booking is not checked in"))
+		}
+
+		if err := repos.Rooms.LockRoom(ctx, b.RoomID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Join(ErrNotFound, newError("room_not_found", "room not found"))
+			}
+			return err
+		}
+		room, err := repos.Rooms.GetRoomByID(ctx, b.RoomID)
+		if err != nil {
+			return err
+		}
+		if checkIn && room.IsOccupied {
+			return errors.Join(ErrConflict, newError("room_occupied", "room is already occupied"))
+		}
+
+		status := "false"
+		roomPatch := dto.RoomPatch{IsOccupied: &checkIn}
+		if checkIn {
+			status = "true"
+		} else {
+			needCleaning := true
+			roomPatch.NeedCleaning = &needCleaning
+		}
+
+		patch := dto.BookingPatch{
+			ID:         &b.ID,
+			RoomID:     &b.RoomID,
+			GuestID:    &b.GuestID,
+			Start_date: &b.Start_date,
+			End_date:   &b.End_date,
+			Status:     &status,
+		}
+		newVersion, err := repos.Bookings.PatchBooking(ctx, version, patch)
+		if err != nil {
+			return repoWriteErr(err)
+		}
+		if _, err := repos.Rooms.PatchRoom(ctx, room.ID, room.Version, roomPatch); err != nil {
+			return repoWriteErr(err)
+		}
+
+		b.Status = status
+		b.Version = newVersion
+		updated = b
+		return nil
+	})
+	if err != nil {
+		if IsValidationErr(err) || IsConflictErr(err) || IsNotFoundErr(err) || IsPreconditionErr(err) {
+			log.Warn("stay change rejected",
+				"op", op,
+				"booking_id", id,
+				"error", err.Error(),
+			)
+			return model.Booking{}, err
+		}
+		log.Error("failed to change stay",
+			"op", op,
+			"booking_id", id,
+			"error", err.Error(),
+		)
+		return model.Booking{}, err
+	}
+
+	log.Info("stay changed successfully",
+		"op", op,
+		"booking_id", id,
+		"room_id", updated.RoomID,
+		"version", updated.Version,
+	)
+	return updated, nil
+}
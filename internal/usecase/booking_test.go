@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"io"
 	"log/slog"
 	"testing"
@@ -9,6 +11,8 @@ import (
 
 	"golangHotelProject/internal/delivery/handlers/dto"
 	"golangHotelProject/internal/model"
+	repo "golangHotelProject/internal/repository"
+	"golangHotelProject/internal/validation"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,13 +23,23 @@ func testBookingLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
 
+// newTestBookingUsecase wires the usecase to mocks that stand in both for the
+// pool and for the transaction.
+func newTestBookingUsecase(bookings *MockBookingRepository, rooms *MockRoomRepository) *BookingUsecase {
+	uow := repo.Bound(repo.Repositories{Rooms: rooms, Bookings: bookings})
+	return NewBookingUsecase(bookings, uow, testBookingLogger())
+}
+
 type MockBookingRepository struct {
 	mock.Mock
 }
 
-func (m *MockBookingRepository) CreateBooking(ctx context.Context, b model.Booking) error {
+func (m *MockBookingRepository) CreateBooking(ctx context.Context, b model.Booking) (model.Booking, error) {
 	args := m.Called(ctx, b)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return model.Booking{}, args.Error(1)
+	}
+	return args.Get(0).(model.Booking), args.Error(1)
 }
 
 func (m *MockBookingRepository) GettingStatus(ctx context.Context, guestID int) (bool, error) {
@@ -38,6 +52,11 @@ func (m *MockBookingRepository) ArrivalStatusOfRoom(ctx context.Context, roomID
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockBookingRepository) HasOverlap(ctx context.Context, roomID int, start, end time.Time, excludeID int) (bool, error) {
+	args := m.Called(ctx, roomID, start, end, excludeID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockBookingRepository) ReadBookingByID(ctx context.Context, id int) (model.Booking, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -46,9 +65,9 @@ func (m *MockBookingRepository) ReadBookingByID(ctx context.Context, id int) (mo
 	return args.Get(0).(model.Booking), args.Error(1)
 }
 
-func (m *MockBookingRepository) PatchBooking(ctx context.Context, patch dto.BookingPatch) error {
-	args := m.Called(ctx, patch)
-	return args.Error(0)
+func (m *MockBookingRepository) PatchBooking(ctx context.Context, version int, patch dto.BookingPatch) (int, error) {
+	args := m.Called(ctx, version, patch)
+	return args.Int(0), args.Error(1)
 }
 
 func (m *MockBookingRepository) ListColumn(ctx context.Context) ([]model.Booking, error) {
@@ -68,8 +87,8 @@ func (m *MockBookingRepository) FilterBookings(ctx context.Context, filter map[s
 	return responses, args.Error(1)
 }
 
-func (m *MockBookingRepository) DeleteBooking(ctx context.Context, id int) error {
-	args := m.Called(ctx, id)
+func (m *MockBookingRepository) DeleteBooking(ctx context.Context, id, version int) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
@@ -86,16 +105,24 @@ func TestBookingCreate_Success(t *testing.T) {
 		Status:     "confirmed",
 	}
 
+	rooms := new(MockRoomRepository)
+	rooms.On("LockRoom", mock.Anything, booking.RoomID).Return(nil)
 	mockRepo.On("GettingStatus", mock.Anything, booking.GuestID).Return(false, nil)
 	mockRepo.On("ArrivalStatusOfRoom", mock.Anything, booking.RoomID).Return(false, nil)
-	mockRepo.On("CreateBooking", mock.Anything, booking).Return(nil)
+	mockRepo.On("HasOverlap", mock.Anything, booking.RoomID, start, end, 0).Return(false, nil)
+	stored := booking
+	stored.ID = 5
+	stored.Version = 1
+	mockRepo.On("CreateBooking", mock.Anything, booking).Return(stored, nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, rooms)
 
-	err := uc.CreateBooking(context.Background(), booking)
+	created, err := uc.CreateBooking(context.Background(), booking)
 
 	assert.NoError(t, err)
+	assert.Equal(t, 5, created.ID)
 	mockRepo.AssertExpectations(t)
+	rooms.AssertExpectations(t)
 }
 
 func TestBookingReadByID_Success(t *testing.T) {
@@ -114,7 +141,7 @@ func TestBookingReadByID_Success(t *testing.T) {
 
 	mockRepo.On("ReadBookingByID", mock.Anything, expected.ID).Return(expected, nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
 
 	result, err := uc.ReadByIDUsecase(context.Background(), expected.ID)
 
@@ -123,6 +150,32 @@ func TestBookingReadByID_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestBookingReadByID_NotFoundAndReadFailure(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	mockRepo.On("ReadBookingByID", mock.Anything, 1).Return(model.Booking{}, sql.ErrNoRows)
+	mockRepo.On("ReadBookingByID", mock.Anything, 2).Return(model.Booking{}, errors.New("connection reset"))
+
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
+
+	_, err := uc.ReadByIDUsecase(context.Background(), 1)
+	assert.True(t, IsNotFoundErr(err))
+
+	_, err = uc.ReadByIDUsecase(context.Background(), 2)
+	assert.Equal(t, "internal", ErrorKind(err), "a failed read is not a missing booking")
+}
+
+func TestBookingPatchByID_MissingBookingIsNotFound(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	id := 9
+	mockRepo.On("ReadBookingByID", mock.Anything, id).Return(model.Booking{}, sql.ErrNoRows)
+
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
+
+	_, err := uc.PatchBookingByID(context.Background(), 1, dto.BookingPatch{ID: &id})
+	assert.True(t, IsNotFoundErr(err))
+	mockRepo.AssertNotCalled(t, "PatchBooking")
+}
+
 func TestBookingPatchByID_Success(t *testing.T) {
 	mockRepo := new(MockBookingRepository)
 
@@ -143,15 +196,19 @@ func TestBookingPatchByID_Success(t *testing.T) {
 		Status: &newStatus,
 	}
 
+	rooms := new(MockRoomRepository)
+	rooms.On("LockRoom", mock.Anything, oldBooking.RoomID).Return(nil)
 	mockRepo.On("ReadBookingByID", mock.Anything, oldBooking.ID).Return(oldBooking, nil)
-	mockRepo.On("PatchBooking", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("HasOverlap", mock.Anything, oldBooking.RoomID, mock.Anything, mock.Anything, oldBooking.ID).Return(false, nil)
+	mockRepo.On("PatchBooking", mock.Anything, 1, mock.Anything).Return(2, nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, rooms)
 
-	err := uc.PatchBookingByID(context.Background(), patch)
+	version, err := uc.PatchBookingByID(context.Background(), 1, patch)
 
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "PatchBooking", mock.Anything, mock.MatchedBy(func(p dto.BookingPatch) bool {
+	assert.Equal(t, 2, version)
+	mockRepo.AssertCalled(t, "PatchBooking", mock.Anything, 1, mock.MatchedBy(func(p dto.BookingPatch) bool {
 		return p.ID != nil && *p.ID == oldBooking.ID && p.Status != nil && *p.Status == newStatus
 	}))
 }
@@ -172,7 +229,7 @@ func TestBookingGetList_Success(t *testing.T) {
 
 	mockRepo.On("ListColumn", mock.Anything).Return(bookings, nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
 
 	result, err := uc.GetList(context.Background())
 
@@ -194,7 +251,7 @@ func TestBookingGetFiltered_Success(t *testing.T) {
 
 	mockRepo.On("FilterBookings", mock.Anything, filter).Return(expected, nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
 
 	result, err := uc.GetFilteredBookings(context.Background(), filter)
 
@@ -206,12 +263,248 @@ func TestBookingGetFiltered_Success(t *testing.T) {
 func TestBookingRemove_Success(t *testing.T) {
 	mockRepo := new(MockBookingRepository)
 
-	mockRepo.On("DeleteBooking", mock.Anything, 1).Return(nil)
+	mockRepo.On("DeleteBooking", mock.Anything, 1, 1).Return(nil)
 
-	uc := NewBookingUsecase(mockRepo, testBookingLogger())
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
 
-	err := uc.RemoveBooking(context.Background(), 1)
+	err := uc.RemoveBooking(context.Background(), 1, 1)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestBookingPatchByID_StaleVersion(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+
+	now := time.Now()
+	oldBooking := model.Booking{
+		ID:         1,
+		RoomID:     1,
+		GuestID:    2,
+		Start_date: now,
+		End_date:   now.Add(24 * time.Hour),
+		Status:     "confirmed",
+		Version:    4,
+	}
+
+	newStatus := "cancelled"
+	patch := dto.BookingPatch{
+		ID:     &oldBooking.ID,
+		Status: &newStatus,
+	}
+
+	rooms := new(MockRoomRepository)
+	rooms.On("LockRoom", mock.Anything, oldBooking.RoomID).Return(nil)
+	mockRepo.On("ReadBookingByID", mock.Anything, oldBooking.ID).Return(oldBooking, nil)
+	mockRepo.On("HasOverlap", mock.Anything, oldBooking.RoomID, mock.Anything, mock.Anything, oldBooking.ID).Return(false, nil)
+	mockRepo.On("PatchBooking", mock.Anything, 3, mock.Anything).Return(0, repo.ErrVersionMismatch)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.PatchBookingByID(context.Background(), 3, patch)
+
+	assert.True(t, IsPreconditionErr(err))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchBookingByID_MissingID(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
+
+	var err error
+	assert.NotPanics(t, func() {
+		_, err = uc.PatchBookingByID(context.Background(), 1, dto.BookingPatch{})
+	})
+
+	assert.True(t, IsValidationErr(err))
+	mockRepo.AssertNotCalled(t, "PatchBooking")
+}
+
+func TestBookingCreate_RoomDoesNotExist(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	start := time.Now()
+	booking := model.Booking{
+		RoomID:     42,
+		GuestID:    10,
+		Start_date: start,
+		End_date:   start.Add(24 * time.Hour),
+	}
+
+	rooms.On("LockRoom", mock.Anything, 42).Return(sql.ErrNoRows)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CreateBooking(context.Background(), booking)
+
+	assert.True(t, IsValidationErr(err))
+	mockRepo.AssertNotCalled(t, "CreateBooking")
+}
+
+func TestBookingCheckIn_Success(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	start := time.Now()
+	booking := model.Booking{
+		ID:         1,
+		RoomID:     3,
+		GuestID:    10,
+		Start_date: start,
+		End_date:   start.Add(24 * time.Hour),
+		Status:     "false",
+		Version:    2,
+	}
+	room := model.Room{ID: 3, Version: 5}
+
+	mockRepo.On("ReadBookingByID", mock.Anything, 1).Return(booking, nil)
+	rooms.On("LockRoom", mock.Anything, 3).Return(nil)
+	rooms.On("GetRoomByID", mock.Anything, 3).Return(room, nil)
+	mockRepo.On("PatchBooking", mock.Anything, 2, mock.MatchedBy(func(p dto.BookingPatch) bool {
+		return *p.Status == "true"
+	})).Return(3, nil)
+	rooms.On("PatchRoom", mock.Anything, 3, 5, mock.MatchedBy(func(p dto.RoomPatch) bool {
+		return p.IsOccupied != nil && *p.IsOccupied
+	})).Return(6, nil)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	updated, err := uc.CheckIn(context.Background(), 1, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "true", updated.Status)
+	assert.Equal(t, 3, updated.Version)
+	mockRepo.AssertExpectations(t)
+	rooms.AssertExpectations(t)
+}
+
+func TestBookingCheckIn_DeletedRoomIsNotFound(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	mockRepo.On("ReadBookingByID", mock.Anything, 1).Return(model.Booking{ID: 1, RoomID: 3, Status: "false", Version: 2}, nil)
+	rooms.On("LockRoom", mock.Anything, 3).Return(sql.ErrNoRows)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CheckIn(context.Background(), 1, 2)
+
+	assert.True(t, IsNotFoundErr(err))
+	var ue *Error
+	if assert.ErrorAs(t, err, &ue) {
+		assert.Equal(t, "room_not_found", ue.Code)
+	}
+	mockRepo.AssertNotCalled(t, "PatchBooking")
+}
+
+func TestBookingCheckIn_ConcurrentRoomEditIsPrecondition(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	mockRepo.On("ReadBookingByID", mock.Anything, 1).Return(model.Booking{ID: 1, RoomID: 3, Status: "false", Version: 2}, nil)
+	rooms.On("LockRoom", mock.Anything, 3).Return(nil)
+	rooms.On("GetRoomByID", mock.Anything, 3).Return(model.Room{ID: 3, Version: 5}, nil)
+	mockRepo.On("PatchBooking", mock.Anything, 2, mock.Anything).Return(3, nil)
+	rooms.On("PatchRoom", mock.Anything, 3, 5, mock.Anything).Return(0, repo.ErrVersionMismatch)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CheckIn(context.Background(), 1, 2)
+
+	assert.True(t, IsPreconditionErr(err))
+}
+
+func TestBookingCheckOut_NotCheckedIn(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	mockRepo.On("ReadBookingByID", mock.Anything, 1).Return(model.Booking{ID: 1, RoomID: 3, Status: "false", Version: 1}, nil)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CheckOut(context.Background(), 1, 1)
+
+	assert.True(t, IsConflictErr(err))
+	mockRepo.AssertNotCalled(t, "PatchBooking")
+	rooms.AssertNotCalled(t, "PatchRoom")
+}
+
+func TestBookingCreate_OverlapIsConflict(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	start := time.Now()
+	end := start.Add(48 * time.Hour)
+	booking := model.Booking{
+		RoomID:     1,
+		GuestID:    10,
+		Start_date: start,
+		End_date:   end,
+	}
+
+	rooms.On("LockRoom", mock.Anything, 1).Return(nil)
+	mockRepo.On("GettingStatus", mock.Anything, 10).Return(false, nil)
+	mockRepo.On("ArrivalStatusOfRoom", mock.Anything, 1).Return(false, nil)
+	mockRepo.On("HasOverlap", mock.Anything, 1, start, end, 0).Return(true, nil)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CreateBooking(context.Background(), booking)
+
+	assert.True(t, IsConflictErr(err))
+	mockRepo.AssertNotCalled(t, "CreateBooking")
+}
+
+func TestBookingCreate_StorageRejectsOverlap(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	rooms := new(MockRoomRepository)
+
+	start := time.Now()
+	end := start.Add(48 * time.Hour)
+	booking := model.Booking{
+		RoomID:     1,
+		GuestID:    10,
+		Start_date: start,
+		End_date:   end,
+	}
+
+	rooms.On("LockRoom", mock.Anything, 1).Return(nil)
+	mockRepo.On("GettingStatus", mock.Anything, 10).Return(false, nil)
+	mockRepo.On("ArrivalStatusOfRoom", mock.Anything, 1).Return(false, nil)
+	mockRepo.On("HasOverlap", mock.Anything, 1, start, end, 0).Return(false, nil)
+	mockRepo.On("CreateBooking", mock.Anything, booking).Return(nil, repo.ErrConflict)
+
+	uc := newTestBookingUsecase(mockRepo, rooms)
+
+	_, err := uc.CreateBooking(context.Background(), booking)
+
+	assert.True(t, IsConflictErr(err))
+}
+
+func TestBookingCreate_ReportsEveryInvalidField(t *testing.T) {
+	mockRepo := new(MockBookingRepository)
+	uc := newTestBookingUsecase(mockRepo, new(MockRoomRepository))
+
+	start := time.Now()
+	_, err := uc.CreateBooking(context.Background(), model.Booking{
+		RoomID:     0,
+		GuestID:    -3,
+		Start_date: start,
+		End_date:   start.Add(-time.Hour),
+	})
+
+	assert.True(t, IsValidationErr(err))
+	assert.Equal(t, validation.Errors{
+		{Field: "room_id", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "guest_id", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "end_date", Code: "after_start", Message: "must be after the start date"},
+	}, validation.Fields(err))
+
+	_, err = uc.CreateBooking(context.Background(), model.Booking{RoomID: 1, GuestID: 1})
+	assert.Equal(t, validation.Errors{
+		{Field: "start_date", Code: "required", Message: "is required"},
+		{Field: "end_date", Code: "required", Message: "is required"},
+	}, validation.Fields(err), "missing dates are not also reported as out of order")
+	mockRepo.AssertNotCalled(t, "CreateBooking")
+}
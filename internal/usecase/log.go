@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"context"
+	"golangHotelProject/internal/logger"
+	"log/slog"
+)
+
+// requestLogger is the logger of one usecase call: the request-scoped logger
+// from ctx tagged with component, so the lines carry the request ID, or base
+// outside a request.
+func requestLogger(ctx context.Context, base *slog.Logger, component string) *slog.Logger {
+	l := logger.FromContextOr(ctx, nil)
+	if l == nil {
+		l = base
+	} else {
+		l = l.With("component", component)
+	}
+	return l.With(logger.TraceAttrs(ctx)...)
+}
+
+func (uc *RoomUsecase) logFor(ctx context.Context) *slog.Logger {
+	return requestLogger(ctx, uc.Logger, "RoomUsecase")
+}
+
+func (uc *BookingUsecase) logFor(ctx context.Context) *slog.Logger {
+	return requestLogger(ctx, uc.Logger, "BookingUsecase")
+}
+
+func (uc *BatchUsecase) logFor(ctx context.Context) *slog.Logger {
+	return requestLogger(ctx, uc.Logger, "BatchUsecase")
+}
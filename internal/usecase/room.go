@@ -2,24 +2,104 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"golangHotelProject/internal/delivery/handlers/dto"
 	"golangHotelProject/internal/logger"
 	md "golangHotelProject/internal/model"
 	repo "golangHotelProject/internal/repository"
+	"golangHotelProject/internal/validation"
 	"log/slog"
 )
 
 var (
-	ErrValidation = errors.New("validation error")
-	ErrConflict   = errors.New("conflict error")
+	ErrValidation   = errors.New("validation error")
+	ErrConflict     = errors.New("conflict error")
+	ErrNotFound     = errors.New("not found")
+	ErrPrecondition = errors.New("precondition failed")
 )
 
-func IsValidationErr(err error) bool { return errors.Is(err, ErrValidation) }
-func IsConflictErr(err error) bool   { return errors.Is(err, ErrConflict) }
+func IsValidationErr(err error) bool   { return errors.Is(err, ErrValidation) }
+func IsConflictErr(err error) bool     { return errors.Is(err, ErrConflict) }
+func IsNotFoundErr(err error) bool     { return errors.Is(err, ErrNotFound) }
+func IsPreconditionErr(err error) bool { return errors.Is(err, ErrPrecondition) }
+
+// ErrorKind names the class of a usecase error: validation, conflict,
+// not_found, precondition or internal for anything unclassified.
+func ErrorKind(err error) string {
+	switch {
+	case IsValidationErr(err):
+		return "validation"
+	case IsConflictErr(err):
+		return "conflict"
+	case IsNotFoundErr(err):
+		return "not_found"
+	case IsPreconditionErr(err):
+		return "precondition"
+	default:
+		return "internal"
+	}
+}
+
+// Error is a usecase failure with a stable Code that clients and the API
+// message catalogs key on. Message is the English text; Params fill the
+// localized one in the same order.
+type Error struct {
+	Code    string
+	Message string
+	Params  []any
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func newError(code, format string, params ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, params...), Params: params}
+}
+
+// repoWriteErr classifies the failure of a repository write: a stale
+// If-Match version, a missing row or a constraint the database enforces.
+func repoWriteErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repo.ErrConflict):
+		return errors.Join(ErrConflict, err)
+	case errors.Is(err, repo.ErrVersionMismatch):
+		return errors.Join(ErrPrecondition, err)
+	case errors.Is(err, sql.ErrNoRows):
+		return errors.Join(ErrNotFound, err)
+	}
+	return err
+}
+
+// inTx runs fn in one transaction when uc has a unit of work and on uc.Repo
+// otherwise.
+func (uc *RoomUsecase) inTx(ctx context.Context, fn func(ctx context.Context, rooms repo.RoomRepository) error) error {
+	if uc.UoW == nil {
+		return fn(ctx, uc.Repo)
+	}
+	return uc.UoW.Do(ctx, func(ctx context.Context, repos repo.Repositories) error {
+		return fn(ctx, repos.Rooms)
+	})
+}
+
+// filterErr turns a filter on a column the table lacks into a validation
+// error, so that the client learns which key it got wrong.
+func filterErr(err error) error {
+	var unknown *repo.UnknownColumnError
+	if errors.As(err, &unknown) {
+		return errors.Join(ErrValidation, newError("unknown_filter_column", "unknown filter column %q", unknown.Column))
+	}
+	return err
+}
 
 type RoomUsecase struct {
-	Repo   repo.RoomRepository
+	Repo repo.RoomRepository
+	// UoW runs the operations that check and then write a room in one
+	// transaction. Without it they use Repo directly, which is what a batch
+	// that already holds a transaction wants.
+	UoW    repo.UnitOfWork
 	Logger *slog.Logger
 }
 
@@ -30,169 +110,213 @@ func NewRoomUsecase(repo repo.RoomRepository, log logger.Logger) *RoomUsecase {
 	}
 }
 
-func (uc *RoomUsecase) AddRoom(ctx context.Context, room md.Room) error {
+func (uc *RoomUsecase) AddRoom(ctx context.Context, room md.Room) (_ md.Room, err error) {
 	const op = "AddRoom"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("adding new room",
+	log.Debug("adding new room",
 		"op", op,
 		"room_number", room.Number,
 		"room_type", room.RoomType,
 	)
 
-	err := validateRoom(room)
+	err = validateRoom(room)
 	if err != nil {
-		uc.Logger.Warn("room validation failed",
+		log.Warn("room validation failed",
 			"op", op,
 			"room_number", room.Number,
 			"error", err.Error(),
 		)
-		return errors.Join(ErrValidation, err)
+		return md.Room{}, errors.Join(ErrValidation, err)
 	}
 
 	exists, err := uc.Repo.IsNumberExists(ctx, room.Number)
 	if err != nil {
-		uc.Logger.Error("failed to check room existence",
+		log.Error("failed to check room existence",
 			"op", op,
 			"room_number", room.Number,
 			"error", err.Error(),
 		)
-		return err
+		return md.Room{}, err
 	}
 	if exists {
-		uc.Logger.Warn("room already exists",
+		log.Warn("room already exists",
 			"op", op,
 			"room_number", room.Number,
 		)
-		return errors.Join(ErrConflict, errors.New("room number already exists"))
+		return md.Room{}, errors.Join(ErrConflict, newError("room_number_taken", "room number already exists"))
 	}
 
-	if err := uc.Repo.CreateRoom(ctx, room); err != nil {
-		uc.Logger.Error("failed to create room",
+	created, err := uc.Repo.CreateRoom(ctx, room)
+	if err != nil {
+		log.Error("failed to create room",
 			"op", op,
 			"room_number", room.Number,
 			"error", err.Error(),
 		)
-		return err
+		return md.Room{}, repoWriteErr(err)
 	}
 
-	uc.Logger.Info("room created successfully",
+	log.Info("room created successfully",
 		"op", op,
-		"room_number", room.Number,
+		"room_id", created.ID,
+		"room_number", created.Number,
 	)
-	return nil
+	return created, nil
+}
+
+// roomRules are the rules of the room fields, shared by create and patch.
+var roomRules = struct {
+	Number, RoomCount, Floor, SleepingPlaces []validation.Rule[int]
+	RoomType                                 []validation.Rule[string]
+}{
+	Number:         []validation.Rule[int]{validation.Min(1)},
+	RoomCount:      []validation.Rule[int]{validation.Min(1)},
+	Floor:          []validation.Rule[int]{validation.Min(1)},
+	SleepingPlaces: []validation.Rule[int]{validation.Min(1)},
+	RoomType:       []validation.Rule[string]{validation.OneOf("Standard", "Deluxe", "Suite")},
+}
+
+// checkHousekeeping rejects a room flagged for cleaning while a guest is in it.
+func checkHousekeeping(v *validation.Validator, field string, isOccupied, needCleaning bool) {
+	v.Check(!(isOccupied && needCleaning), field, "occupied", "cannot be set while the room is occupied")
 }
 
 func validateRoom(r md.Room) error {
-	if r.Number <= 0 {
-		return errors.Join(ErrValidation, errors.New("number must be more then 0"))
-	}
-	if r.RoomCount < 1 {
-		return errors.Join(ErrValidation, errors.New("room count must be more then 0"))
-	}
-	if r.SleepingPlaces < 1 {
-		return errors.Join(ErrValidation, errors.New("sleepng places must be more then 0"))
-	}
+	var v validation.Validator
+	validation.Field(&v, "number", r.Number, roomRules.Number...)
+	validation.Field(&v, "room_count", r.RoomCount, roomRules.RoomCount...)
+	validation.Field(&v, "floor", r.Floor, roomRules.Floor...)
+	validation.Field(&v, "sleeping_places", r.SleepingPlaces, roomRules.SleepingPlaces...)
+	validation.Field(&v, "room_type", r.RoomType, roomRules.RoomType...)
+	checkHousekeeping(&v, "need_cleaning", r.IsOccupied, r.NeedCleaning)
+	return v.Err()
+}
 
-	if r.Floor < 1 {
-		return errors.Join(ErrValidation, errors.New("there no underground floors, number must more then 0"))
-	}
-	switch r.RoomType {
-	case "Standard", "Deluxe", "Suite":
-	default:
-		return errors.Join(ErrValidation, errors.New("room_type must be one of: Standard, Deluxe, Suite"))
-	}
-	if r.IsOccupied && r.NeedCleaning {
-		return errors.Join(ErrValidation, errors.New("cannot set need_cleaning while room is occupied"))
+// validateRoomPatch checks the fields p sets, named as in the patch body.
+func validateRoomPatch(p dto.RoomPatch) error {
+	var v validation.Validator
+	validation.Optional(&v, "roomCount", p.RoomCount, roomRules.RoomCount...)
+	validation.Optional(&v, "floor", p.Floor, roomRules.Floor...)
+	validation.Optional(&v, "sleepingPlaces", p.SleepingPlaces, roomRules.SleepingPlaces...)
+	validation.Optional(&v, "roomType", p.RoomType, roomRules.RoomType...)
+	if p.IsOccupied != nil && p.NeedCleaning != nil {
+		checkHousekeeping(&v, "needCleaning", *p.IsOccupied, *p.NeedCleaning)
 	}
-	return nil
+	return v.Err()
 }
 
-func (uc *RoomUsecase) PatchRoom(ctx context.Context, id int, p dto.RoomPatch) error {
-	const op = "PatchRoom"
+func (uc *RoomUsecase) GetRoom(ctx context.Context, id int) (_ md.Room, err error) {
+	const op = "GetRoom"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("patching room",
+	log.Debug("reading room by id",
 		"op", op,
 		"room_id", id,
 	)
 
 	if id <= 0 {
-		uc.Logger.Warn("invalid room id",
+		log.Warn("invalid room id",
 			"op", op,
 			"room_id", id,
 		)
-		return errors.Join(ErrValidation, errors.New("invalid id"))
+		return md.Room{}, errors.Join(ErrValidation, newError("invalid_id", "invalid id"))
 	}
 
-	if isEmptyPatch(p) {
-		uc.Logger.Debug("empty patch, skipping update",
+	room, err := uc.Repo.GetRoomByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("room not found",
+				"op", op,
+				"room_id", id,
+			)
+			return md.Room{}, errors.Join(ErrNotFound, newError("room_not_found", "room not found"))
+		}
+		log.Error("failed to read room",
 			"op", op,
 			"room_id", id,
+			"error", err.Error(),
 		)
-		return nil
+		return md.Room{}, err
 	}
 
-	if p.Floor != nil && *p.Floor <= 0 {
-		uc.Logger.Warn("invalid floor value",
+	log.Debug("room retrieved successfully",
+		"op", op,
+		"room_id", id,
+		"version", room.Version,
+	)
+	return room, nil
+}
+
+// PatchRoom applies p to the room if it is still at version and returns the
+// room's new version.
+func (uc *RoomUsecase) PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (_ int, err error) {
+	const op = "PatchRoom"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
+
+	log.Debug("patching room",
+		"op", op,
+		"room_id", id,
+		"version", version,
+	)
+
+	if id <= 0 {
+		log.Warn("invalid room id",
 			"op", op,
 			"room_id", id,
-			"floor", *p.Floor,
 		)
-		return errors.Join(ErrValidation, errors.New("floor must be more then 0"))
+		return 0, errors.Join(ErrValidation, newError("invalid_id", "invalid id"))
 	}
 
-	if p.SleepingPlaces != nil && *p.SleepingPlaces <= 0 {
-		uc.Logger.Warn("invalid sleeping places value",
+	if version <= 0 {
+		log.Warn("invalid room version",
 			"op", op,
 			"room_id", id,
-			"sleeping_places", *p.SleepingPlaces,
+			"version", version,
 		)
-		return errors.Join(ErrValidation, errors.New("sleepng places must be more then 0"))
+		return 0, errors.Join(ErrValidation, newError("invalid_version", "version must be more than 0"))
 	}
-	if p.RoomType != nil {
-		switch *p.RoomType {
-		case "Standard", "Deluxe", "Suite":
-		default:
-			uc.Logger.Warn("invalid room type",
-				"op", op,
-				"room_id", id,
-				"room_type", *p.RoomType,
-			)
-			return errors.Join(ErrValidation, errors.New("room_type must be one of: Standard, Deluxe, Suite"))
-		}
-	}
-	if p.RoomCount != nil && *p.RoomCount <= 0 {
-		uc.Logger.Warn("invalid room count value",
+
+	if isEmptyPatch(p) {
+		log.Debug("empty patch, skipping update",
 			"op", op,
 			"room_id", id,
-			"room_count", *p.RoomCount,
 		)
-		return errors.Join(ErrValidation, errors.New("room count must be more then 0"))
-
+		return version, nil
 	}
-	if p.IsOccupied != nil && p.NeedCleaning != nil {
-		if *p.IsOccupied && *p.NeedCleaning {
-			uc.Logger.Warn("cannot set need_cleaning while room is occupied",
-				"op", op,
-				"room_id", id,
-			)
-			return errors.Join(ErrValidation, errors.New("cannot set need_cleaning while room is occupied"))
-		}
+
+	if err = validateRoomPatch(p); err != nil {
+		log.Warn("room patch validation failed",
+			"op", op,
+			"room_id", id,
+			"error", err.Error(),
+		)
+		return 0, errors.Join(ErrValidation, err)
 	}
 
-	if err := uc.Repo.PatchRoom(ctx, id, p); err != nil {
-		uc.Logger.Error("failed to patch room",
+	newVersion, err := uc.Repo.PatchRoom(ctx, id, version, p)
+	if err != nil {
+		log.Error("failed to patch room",
 			"op", op,
 			"room_id", id,
+			"version", version,
 			"error", err.Error(),
 		)
-		return err
+		return 0, repoWriteErr(err)
 	}
 
-	uc.Logger.Info("room patched successfully",
+	log.Info("room patched successfully",
 		"op", op,
 		"room_id", id,
+		"version", newVersion,
 	)
-	return nil
+	return newVersion, nil
 }
 
 func isEmptyPatch(p dto.RoomPatch) bool {
@@ -204,99 +328,152 @@ func isEmptyPatch(p dto.RoomPatch) bool {
 		p.NeedCleaning == nil
 }
 
-func (uc *RoomUsecase) RemoveRoom(ctx context.Context, id int) error {
+func (uc *RoomUsecase) RemoveRoom(ctx context.Context, id, version int) (err error) {
 	const op = "RemoveRoom"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("removing room",
+	log.Debug("removing room",
 		"op", op,
 		"room_id", id,
+		"version", version,
 	)
 
 	if id <= 0 {
-		uc.Logger.Warn("invalid room id",
+		log.Warn("invalid room id",
 			"op", op,
 			"room_id", id,
 		)
-		return errors.Join(ErrValidation, errors.New("ID must be more than 0"))
+		return errors.Join(ErrValidation, newError("invalid_id", "ID must be more than 0"))
 	}
-	occupied, err := uc.Repo.IsOccupied(ctx, id)
-	if err != nil {
-		uc.Logger.Error("failed to check room occupation status",
+	if version <= 0 {
+		log.Warn("invalid room version",
 			"op", op,
 			"room_id", id,
-			"error", err.Error(),
+			"version", version,
 		)
-		return err
+		return errors.Join(ErrValidation, newError("invalid_version", "version must be more than 0"))
 	}
-	if occupied {
-		uc.Logger.Warn("cannot remove occupied room",
-			"op", op,
-			"room_id", id,
-		)
-		return errors.Join(ErrValidation, errors.New("room is occupied"))
+	err = uc.inTx(ctx, func(ctx context.Context, rooms repo.RoomRepository) error {
+		// Check-in locks the room as well, so it cannot slip in between the
+		// occupancy check and the delete.
+		if err := rooms.LockRoom(ctx, id); err != nil {
+			return err
+		}
+		occupied, err := rooms.IsOccupied(ctx, id)
+		if err != nil {
+			return err
+		}
+		if occupied {
+			log.Warn("cannot remove occupied room",
+				"op", op,
+				"room_id", id,
+			)
+			return errors.Join(ErrValidation, newError("room_occupied", "room is occupied"))
+		}
+		return repoWriteErr(rooms.DeleteRoom(ctx, id, version))
+	})
+	if errors.Is(err, sql.ErrNoRows) && !IsNotFoundErr(err) {
+		err = errors.Join(ErrNotFound, newError("room_not_found", "room not found"))
 	}
-
-	err = uc.Repo.DeleteRoom(ctx, id)
 	if err != nil {
-		uc.Logger.Error("failed to delete room",
-			"op", op,
-			"room_id", id,
-			"error", err.Error(),
-		)
+		if ErrorKind(err) == "internal" {
+			log.Error("failed to remove room",
+				"op", op,
+				"room_id", id,
+				"version", version,
+				"error", err.Error(),
+			)
+		}
 		return err
 	}
 
-	uc.Logger.Info("room removed successfully",
+	log.Info("room removed successfully",
 		"op", op,
 		"room_id", id,
 	)
 	return nil
 }
 
-func (uc *RoomUsecase) GetList(ctx context.Context) ([]md.Room, error) {
+func (uc *RoomUsecase) GetList(ctx context.Context) (_ []md.Room, err error) {
 	const op = "GetList"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("fetching room list", "op", op)
+	log.Debug("fetching room list", "op", op)
 
 	response, err := uc.Repo.ListRoom(ctx)
 	if err != nil {
-		uc.Logger.Error("failed to fetch room list",
+		log.Error("failed to fetch room list",
 			"op", op,
 			"error", err.Error(),
 		)
 		return nil, err
 	}
 	if len(response) == 0 {
-		uc.Logger.Info("room list is empty", "op", op)
-		return response, errors.Join(ErrConflict, errors.New("database is clear"))
+		log.Info("room list is empty", "op", op)
+		return response, errors.Join(ErrConflict, newError("no_rooms", "database is clear"))
 	}
 
-	uc.Logger.Debug("room list fetched successfully",
+	log.Debug("room list fetched successfully",
 		"op", op,
 		"count", len(response),
 	)
 	return response, nil
 }
 
-func (uc *RoomUsecase) GetFilteredRooms(ctx context.Context, filter map[string]interface{}) (map[string][]int, error) {
+// RoomStats is a snapshot of the housekeeping state of the hotel.
+type RoomStats struct {
+	Total        int
+	Occupied     int
+	NeedCleaning int
+}
+
+func (uc *RoomUsecase) Stats(ctx context.Context) (_ RoomStats, err error) {
+	ctx, end := startOp(ctx, "RoomUsecase", "Stats")
+	defer func() { end(err) }()
+
+	rooms, err := uc.Repo.ListRoom(ctx)
+	if err != nil {
+		return RoomStats{}, err
+	}
+
+	stats := RoomStats{Total: len(rooms)}
+	for _, room := range rooms {
+		if room.IsOccupied {
+			stats.Occupied++
+		}
+		if room.NeedCleaning {
+			stats.NeedCleaning++
+		}
+	}
+	return stats, nil
+}
+
+func (uc *RoomUsecase) GetFilteredRooms(ctx context.Context, filter map[string]interface{}) (_ map[string][]int, err error) {
 	const op = "GetFilteredRooms"
+	ctx, end := startOp(ctx, "RoomUsecase", op)
+	defer func() { end(err) }()
+	log := uc.logFor(ctx)
 
-	uc.Logger.Debug("filtering rooms",
+	log.Debug("filtering rooms",
 		"op", op,
 		"filter", filter,
 	)
 
 	response, err := uc.Repo.FilterRoom(ctx, filter)
 	if err != nil {
-		uc.Logger.Error("failed to filter rooms",
+		log.Error("failed to filter rooms",
 			"op", op,
 			"filter", filter,
 			"error", err.Error(),
 		)
-		return nil, err
+		return nil, filterErr(err)
 	}
 
-	uc.Logger.Debug("rooms filtered successfully",
+	log.Debug("rooms filtered successfully",
 		"op", op,
 		"filter", filter,
 		"results_count", len(response),
@@ -2,15 +2,19 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"golangHotelProject/internal/delivery/handlers/dto"
 	md "golangHotelProject/internal/model"
+	repo "golangHotelProject/internal/repository"
+	"golangHotelProject/internal/validation"
 	"io"
 	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // testLogger создает логгер для тестов (отключает вывод)
@@ -22,9 +26,12 @@ type MockRoomRepository struct {
 	mock.Mock
 }
 
-func (m *MockRoomRepository) CreateRoom(ctx context.Context, room md.Room) error {
+func (m *MockRoomRepository) CreateRoom(ctx context.Context, room md.Room) (md.Room, error) {
 	args := m.Called(ctx, room)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return md.Room{}, args.Error(1)
+	}
+	return args.Get(0).(md.Room), args.Error(1)
 }
 
 func (m *MockRoomRepository) IsNumberExists(ctx context.Context, number int) (bool, error) {
@@ -49,13 +56,21 @@ func (m *MockRoomRepository) FilterRoom(ctx context.Context, filter map[string]i
 	return responses, args.Error(1)
 }
 
-func (m *MockRoomRepository) PatchRoom(ctx context.Context, id int, p dto.RoomPatch) error {
-	args := m.Called(ctx, id, p)
-	return args.Error(0)
+func (m *MockRoomRepository) GetRoomByID(ctx context.Context, id int) (md.Room, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return md.Room{}, args.Error(1)
+	}
+	return args.Get(0).(md.Room), args.Error(1)
 }
 
-func (m *MockRoomRepository) DeleteRoom(ctx context.Context, id int) error {
-	args := m.Called(ctx, id)
+func (m *MockRoomRepository) PatchRoom(ctx context.Context, id, version int, p dto.RoomPatch) (int, error) {
+	args := m.Called(ctx, id, version, p)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) DeleteRoom(ctx context.Context, id, version int) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
@@ -64,14 +79,14 @@ func (m *MockRoomRepository) IsOccupied(ctx context.Context, roomID int) (bool,
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockRoomRepository) LockRoom(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func TestCreateRoom_Success(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
-	mockRepo.On("IsNumberExists", mock.Anything, 1).Return(false, nil)
-	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil)
-
-	uc := NewRoomUsecase(mockRepo, testLogger())
-
 	room := md.Room{
 		Number:         1,
 		RoomCount:      1,
@@ -81,10 +96,19 @@ func TestCreateRoom_Success(t *testing.T) {
 		RoomType:       "Standard",
 		NeedCleaning:   false,
 	}
+	stored := room
+	stored.ID = 7
+	stored.Version = 1
 
-	err := uc.AddRoom(context.Background(), room)
+	mockRepo.On("IsNumberExists", mock.Anything, 1).Return(false, nil)
+	mockRepo.On("CreateRoom", mock.Anything, room).Return(stored, nil)
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+
+	created, err := uc.AddRoom(context.Background(), room)
 
 	assert.NoError(t, err)
+	assert.Equal(t, stored, created)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -105,7 +129,7 @@ func TestCreateRoom_NumberAlreadyExists(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "CreateRoom")
@@ -126,7 +150,7 @@ func TestCreateRoom_InvalidNumber(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "IsNumberExists")
@@ -136,7 +160,7 @@ func TestCreateRoom_InvalidNumber(t *testing.T) {
 func TestCreateRoom_InvalidRoomCountNumber(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
-	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil, nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
 
@@ -150,7 +174,7 @@ func TestCreateRoom_InvalidRoomCountNumber(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "CreateRoom")
@@ -171,7 +195,7 @@ func TestCreateRoom_InvalidFloorNumber(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "IsNumberExists")
@@ -193,7 +217,7 @@ func TestCreateRoom_InvalidSleepingPlacesNumber(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "IsNumberExists")
@@ -215,7 +239,7 @@ func TestCreateRoom_RoomTypeIsEmpty(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "IsNumberExists")
@@ -226,7 +250,7 @@ func TestCreateRoom_RoomTypeIsEmpty(t *testing.T) {
 func TestCreateRoom_DatabaseErrorWhenCheckingNumberExisting(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 	mockRepo.On("IsNumberExists", mock.Anything, 1).Return(false, errors.New("database connection failed"))
-	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil, nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
 
@@ -240,7 +264,7 @@ func TestCreateRoom_DatabaseErrorWhenCheckingNumberExisting(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "CreateRoom")
@@ -249,7 +273,7 @@ func TestCreateRoom_DatabaseErrorWhenCheckingNumberExisting(t *testing.T) {
 func TestCreateRoom_DatabaseErrorWhenCreatingRoom(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 	mockRepo.On("IsNumberExists", mock.Anything, 1).Return(false, nil)
-	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(errors.New("database connection failed"))
+	mockRepo.On("CreateRoom", mock.Anything, mock.Anything).Return(nil, errors.New("database connection failed"))
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
 
@@ -263,7 +287,7 @@ func TestCreateRoom_DatabaseErrorWhenCreatingRoom(t *testing.T) {
 		NeedCleaning:   false,
 	}
 
-	err := uc.AddRoom(context.Background(), room)
+	_, err := uc.AddRoom(context.Background(), room)
 
 	assert.Error(t, err)
 }
@@ -404,6 +428,20 @@ func TestGetFilteredRooms_DatabaseError(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetFilteredRooms_UnknownColumnIsValidationError(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	filter := map[string]interface{}{"floor; DROP TABLE rooms": 1}
+
+	mockRepo.On("FilterRoom", mock.Anything, filter).Return(map[string][]int(nil), &repo.UnknownColumnError{Column: "floor; DROP TABLE rooms"})
+	uc := NewRoomUsecase(mockRepo, testLogger())
+
+	_, err := uc.GetFilteredRooms(context.Background(), filter)
+	assert.True(t, IsValidationErr(err))
+	var ue *Error
+	require.ErrorAs(t, err, &ue)
+	assert.Equal(t, "unknown_filter_column", ue.Code)
+}
+
 func TestGetFilteredRooms_Multiplyfilters(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 	filter := map[string]interface{}{
@@ -433,11 +471,12 @@ func TestRemoveRoom_SuccessRemove(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
 	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(nil)
 	mockRepo.On("IsOccupied", mock.Anything, id).Return(false, nil)
-	mockRepo.On("DeleteRoom", mock.Anything, id).Return(nil)
+	mockRepo.On("DeleteRoom", mock.Anything, id, 1).Return(nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.RemoveRoom(context.Background(), id)
+	err := uc.RemoveRoom(context.Background(), id, 1)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
@@ -448,7 +487,7 @@ func TestRemoveRoom_InvalidID(t *testing.T) {
 	id := 0
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.RemoveRoom(context.Background(), id)
+	err := uc.RemoveRoom(context.Background(), id, 1)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "ID must be more than 0")
 	mockRepo.AssertNotCalled(t, "IsOccupied")
@@ -459,10 +498,11 @@ func TestRemoveRoom_RoomOccupied(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
 	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(nil)
 	mockRepo.On("IsOccupied", mock.Anything, id).Return(true, nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.RemoveRoom(context.Background(), id)
+	err := uc.RemoveRoom(context.Background(), id, 1)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "room is occupied")
@@ -474,24 +514,38 @@ func TestRemoveRoom_RoomOccupiedDatabaseError(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
 	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(nil)
 	mockRepo.On("IsOccupied", mock.Anything, id).Return(false, errors.New("db error"))
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.RemoveRoom(context.Background(), id)
+	err := uc.RemoveRoom(context.Background(), id, 1)
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "DeleteRoom")
 	mockRepo.AssertExpectations(t)
 }
 
+func TestRemoveRoom_MissingRoomIsNotFound(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+
+	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(sql.ErrNoRows)
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+	err := uc.RemoveRoom(context.Background(), id, 1)
+	assert.True(t, IsNotFoundErr(err))
+	mockRepo.AssertNotCalled(t, "DeleteRoom")
+}
+
 func TestRemoveRoom_DeleteRoomDatabaseError(t *testing.T) {
 	mockRepo := new(MockRoomRepository)
 
 	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(nil)
 	mockRepo.On("IsOccupied", mock.Anything, id).Return(false, nil)
-	mockRepo.On("DeleteRoom", mock.Anything, id).Return(errors.New("db error"))
+	mockRepo.On("DeleteRoom", mock.Anything, id, 1).Return(errors.New("db error"))
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.RemoveRoom(context.Background(), id)
+	err := uc.RemoveRoom(context.Background(), id, 1)
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
 }
@@ -516,10 +570,10 @@ func TestPatchRoom(t *testing.T) {
 		NeedCleaning:   &needCleaning,
 	}
 
-	mockRepo.On("PatchRoom", mock.Anything, id, patch).Return(nil)
+	mockRepo.On("PatchRoom", mock.Anything, id, 1, patch).Return(2, nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
@@ -534,10 +588,10 @@ func TestPatchRoom_OnlyFloor(t *testing.T) {
 		Floor: &floor,
 	}
 
-	mockRepo.On("PatchRoom", mock.Anything, id, patch).Return(nil)
+	mockRepo.On("PatchRoom", mock.Anything, id, 1, patch).Return(2, nil)
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
@@ -550,7 +604,7 @@ func TestPatchRoom_EmptyPatch(t *testing.T) {
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
 
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.NoError(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -573,7 +627,7 @@ func TestPatchRoom_InvalidID(t *testing.T) {
 			floor := 2
 			patch := dto.RoomPatch{Floor: &floor}
 
-			err := uc.PatchRoom(context.Background(), tt.id, patch)
+			_, err := uc.PatchRoom(context.Background(), tt.id, 1, patch)
 
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "invalid id")
@@ -591,7 +645,7 @@ func TestPatchRoom_InvalidFloor(t *testing.T) {
 	}
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -607,7 +661,7 @@ func TestPatchRoom_InvalidSleepingPlaces(t *testing.T) {
 	}
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -623,7 +677,7 @@ func TestPatchRoom_InvalidRoomType(t *testing.T) {
 	}
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -639,7 +693,7 @@ func TestPatchRoom_InvalidRoomCount(t *testing.T) {
 	}
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -657,7 +711,7 @@ func TestPatchRoom_InvalidDatas(t *testing.T) {
 	}
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 
 	assert.Error(t, err)
 	mockRepo.AssertNotCalled(t, "PatchRoom")
@@ -674,10 +728,10 @@ func TestPatchRoom_DataBaseError(t *testing.T) {
 		Floor: &floor,
 	}
 
-	mockRepo.On("PatchRoom", mock.Anything, id, patch).Return(errors.New("databese error"))
+	mockRepo.On("PatchRoom", mock.Anything, id, 1, patch).Return(0, errors.New("databese error"))
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
 }
@@ -692,10 +746,119 @@ func TestPatchRoom_RoomNotFound(t *testing.T) {
 		Floor: &floor,
 	}
 
-	mockRepo.On("PatchRoom", mock.Anything, id, patch).Return(errors.New("room not found"))
+	mockRepo.On("PatchRoom", mock.Anything, id, 1, patch).Return(0, errors.New("room not found"))
 
 	uc := NewRoomUsecase(mockRepo, testLogger())
-	err := uc.PatchRoom(context.Background(), id, patch)
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestPatchRoom_StaleVersion(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+
+	id := 1
+	floor := 2
+
+	patch := dto.RoomPatch{
+		Floor: &floor,
+	}
+
+	mockRepo.On("PatchRoom", mock.Anything, id, 1, patch).Return(0, repo.ErrVersionMismatch)
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+	_, err := uc.PatchRoom(context.Background(), id, 1, patch)
+	assert.True(t, IsPreconditionErr(err))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchRoom_InvalidVersion(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+
+	floor := 2
+	patch := dto.RoomPatch{Floor: &floor}
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+	_, err := uc.PatchRoom(context.Background(), 1, 0, patch)
+
+	assert.True(t, IsValidationErr(err))
+	mockRepo.AssertNotCalled(t, "PatchRoom")
+}
+
+func TestRemoveRoom_StaleVersion(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+
+	id := 1
+	mockRepo.On("LockRoom", mock.Anything, id).Return(nil)
+	mockRepo.On("IsOccupied", mock.Anything, id).Return(false, nil)
+	mockRepo.On("DeleteRoom", mock.Anything, id, 3).Return(repo.ErrVersionMismatch)
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+	err := uc.RemoveRoom(context.Background(), id, 3)
+	assert.True(t, IsPreconditionErr(err))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRoomStats(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	rooms := []md.Room{
+		{ID: 1, IsOccupied: true},
+		{ID: 2, IsOccupied: true, NeedCleaning: true},
+		{ID: 3, NeedCleaning: true},
+		{ID: 4},
+	}
+	mockRepo.On("ListRoom", mock.Anything).Return(rooms, nil)
+
+	uc := NewRoomUsecase(mockRepo, testLogger())
+	stats, err := uc.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, RoomStats{Total: 4, Occupied: 2, NeedCleaning: 2}, stats)
+}
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, "validation", ErrorKind(errors.Join(ErrValidation, errors.New("bad"))))
+	assert.Equal(t, "conflict", ErrorKind(repoWriteErr(repo.ErrConflict)))
+	assert.Equal(t, "precondition", ErrorKind(repoWriteErr(repo.ErrVersionMismatch)))
+	assert.Equal(t, "not_found", ErrorKind(repoWriteErr(sql.ErrNoRows)))
+	assert.Equal(t, "internal", ErrorKind(errors.New("connection reset")))
+}
+
+func TestAddRoom_ReportsEveryInvalidField(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	uc := NewRoomUsecase(mockRepo, testLogger())
+
+	_, err := uc.AddRoom(context.Background(), md.Room{
+		Number:         101,
+		RoomCount:      0,
+		Floor:          -1,
+		SleepingPlaces: 2,
+		RoomType:       "Palace",
+		IsOccupied:     true,
+		NeedCleaning:   true,
+	})
+
+	assert.True(t, IsValidationErr(err))
+	assert.Equal(t, validation.Errors{
+		{Field: "room_count", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "floor", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "room_type", Code: "one_of", Message: "must be one of: Standard, Deluxe, Suite", Params: []any{"Standard, Deluxe, Suite"}},
+		{Field: "need_cleaning", Code: "occupied", Message: "cannot be set while the room is occupied"},
+	}, validation.Fields(err))
+	mockRepo.AssertNotCalled(t, "IsNumberExists")
+}
+
+func TestPatchRoom_SharesTheCreateRules(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	uc := NewRoomUsecase(mockRepo, testLogger())
+
+	zero, palace := 0, "Palace"
+	_, err := uc.PatchRoom(context.Background(), 1, 1, dto.RoomPatch{SleepingPlaces: &zero, RoomType: &palace})
+
+	assert.True(t, IsValidationErr(err))
+	assert.Equal(t, validation.Errors{
+		{Field: "sleepingPlaces", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "roomType", Code: "one_of", Message: "must be one of: Standard, Deluxe, Suite", Params: []any{"Standard, Deluxe, Suite"}},
+	}, validation.Fields(err))
+	mockRepo.AssertNotCalled(t, "PatchRoom")
+}
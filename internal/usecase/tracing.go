@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("golangHotelProject/internal/usecase")
+
+// startOp starts the span of a usecase operation, named like the
+// component and op the usecase logs with. The returned function ends the
+// span and must get the error the operation returns. Only internal errors
+// mark the span as failed; the others are the caller's fault and are
+// recorded as the error.kind attribute.
+func startOp(ctx context.Context, component, op string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, component+"."+op, trace.WithAttributes(
+		attribute.String("hotel.component", component),
+		attribute.String("hotel.op", op),
+	))
+	return ctx, func(err error) {
+		if err != nil {
+			kind := ErrorKind(err)
+			span.SetAttributes(attribute.String("error.kind", kind))
+			if kind == "internal" {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,133 @@
+// Package validation checks values against declared rules and collects every
+// broken rule instead of stopping at the first one.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError is one broken rule. Code is stable and meant for programs,
+// Message for people. Params are the values the message was built from, so
+// that it can be rebuilt in another language.
+type FieldError struct {
+	Field   string `json:"field" example:"floor"`
+	Code    string `json:"code" example:"min"`
+	Message string `json:"message" example:"must be at least 1"`
+	Params  []any  `json:"-"`
+}
+
+func (e FieldError) String() string { return e.Field + ": " + e.Message }
+
+// Errors are the broken rules of one value in the order they were checked.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Fields returns the field errors in err's tree, or nil.
+func Fields(err error) Errors {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return nil
+}
+
+// Rule is a named check of a single value.
+type Rule[T any] struct {
+	Code    string
+	Message string
+	Params  []any
+	Valid   func(T) bool
+}
+
+// Validator collects the errors of one value. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Check records an error for field unless ok. It is for rules that involve
+// several fields.
+func (v *Validator) Check(ok bool, field, code, message string, params ...any) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: message, Params: params})
+	}
+}
+
+// Err returns the collected errors as Errors, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Field checks value against rules and records the first rule it breaks.
+func Field[T any](v *Validator, field string, value T, rules ...Rule[T]) {
+	for _, r := range rules {
+		if !r.Valid(value) {
+			v.Check(false, field, r.Code, r.Message, r.Params...)
+			return
+		}
+	}
+}
+
+// Optional is Field for a value a patch may leave out; nil is valid.
+func Optional[T any](v *Validator, field string, value *T, rules ...Rule[T]) {
+	if value != nil {
+		Field(v, field, *value, rules...)
+	}
+}
+
+func Min(n int) Rule[int] {
+	return Rule[int]{
+		Code:    "min",
+		Message: fmt.Sprintf("must be at least %d", n),
+		Params:  []any{n},
+		Valid:   func(v int) bool { return v >= n },
+	}
+}
+
+func OneOf[T comparable](allowed ...T) Rule[T] {
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		names[i] = fmt.Sprint(a)
+	}
+	list := strings.Join(names, ", ")
+	return Rule[T]{
+		Code:    "one_of",
+		Message: "must be one of: " + list,
+		Params:  []any{list},
+		Valid: func(v T) bool {
+			for _, a := range allowed {
+				if v == a {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Required rejects the zero value, e.g. a missing date. Types with an
+// IsZero method, like time.Time, decide themselves.
+func Required[T comparable]() Rule[T] {
+	return Rule[T]{
+		Code:    "required",
+		Message: "is required",
+		Valid: func(v T) bool {
+			if z, ok := any(v).(interface{ IsZero() bool }); ok {
+				return !z.IsZero()
+			}
+			var zero T
+			return v != zero
+		},
+	}
+}
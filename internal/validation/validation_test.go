@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_CollectsEveryField(t *testing.T) {
+	var v Validator
+	Field(&v, "floor", 0, Min(1))
+	Field(&v, "room_type", "Palace", OneOf("Standard", "Suite"))
+	Field(&v, "number", 101, Min(1))
+	v.Check(false, "need_cleaning", "occupied", "cannot be set while the room is occupied")
+
+	err := v.Err()
+
+	assert.Equal(t, Errors{
+		{Field: "floor", Code: "min", Message: "must be at least 1", Params: []any{1}},
+		{Field: "room_type", Code: "one_of", Message: "must be one of: Standard, Suite", Params: []any{"Standard, Suite"}},
+		{Field: "need_cleaning", Code: "occupied", Message: "cannot be set while the room is occupied"},
+	}, err)
+	assert.EqualError(t, err, "floor: must be at least 1; room_type: must be one of: Standard, Suite; need_cleaning: cannot be set while the room is occupied")
+}
+
+func TestField_ReportsOnlyTheFirstBrokenRule(t *testing.T) {
+	var v Validator
+	Field(&v, "floor", -1, Min(0), Min(1))
+
+	assert.Equal(t, Errors{{Field: "floor", Code: "min", Message: "must be at least 0", Params: []any{0}}}, v.Err())
+}
+
+func TestOptional_SkipsMissingValues(t *testing.T) {
+	var v Validator
+	zero := 0
+	Optional[int](&v, "floor", nil, Min(1))
+	assert.NoError(t, v.Err())
+
+	Optional(&v, "floor", &zero, Min(1))
+	assert.Len(t, Fields(v.Err()), 1)
+}
+
+func TestRequired(t *testing.T) {
+	assert.False(t, Required[time.Time]().Valid(time.Time{}))
+	assert.False(t, Required[time.Time]().Valid(time.Time{}.In(time.FixedZone("MSK", 3*3600))), "IsZero decides")
+	assert.True(t, Required[time.Time]().Valid(time.Now()))
+	assert.False(t, Required[string]().Valid(""))
+}
+
+func TestFields_FindsWrappedErrors(t *testing.T) {
+	var v Validator
+	Field(&v, "floor", 0, Min(1))
+	wrapped := fmt.Errorf("patch room: %w", errors.Join(errors.New("validation error"), v.Err()))
+
+	assert.Equal(t, "floor", Fields(wrapped)[0].Field)
+	assert.Nil(t, Fields(errors.New("conflict")))
+}
@@ -1,18 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	_ "golangHotelProject/docs"
-	hn "golangHotelProject/internal/delivery/handlers"
+	"golangHotelProject/internal/app"
+	"golangHotelProject/internal/config"
 	"golangHotelProject/internal/logger"
-	"golangHotelProject/internal/repository"
-	"golangHotelProject/internal/repository/db"
-	"golangHotelProject/internal/usecase"
-	"log"
+	"golangHotelProject/internal/tracing"
 	"log/slog"
-	"net/http"
 	"os"
-
-	httpSwagger "github.com/swaggo/http-swagger"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // @title Hotel Booking API
@@ -22,96 +24,104 @@ import (
 // @host localhost:8080
 // @BasePath /
 
-func withCORS(next http.Handler) http.Handler {
-	allowed := map[string]bool{
-		"http://localhost:3000": true,
+const usage = "usage: server [flags] [migrate ... | config print]"
+
+func main() {
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, usage)
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(2)
 	}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin != "" && allowed[origin] {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
+	// Инициализация логгера
+	logOpts := logger.Options{
+		Level:          cfg.Log.Level,
+		Format:         cfg.Log.Format,
+		Components:     cfg.Log.Components,
+		File:           cfg.Log.File,
+		FileMaxSizeMB:  cfg.Log.FileMaxSizeMB,
+		FileMaxBackups: cfg.Log.FileMaxBackups,
+	}
+	if r := cfg.Log.Redact; r.Enabled {
+		logOpts.Redact = &logger.RedactOptions{
+			Keys:     r.Keys,
+			Patterns: r.Patterns,
+			Hash:     r.Mode == "hash",
+			HashSalt: r.HashSalt,
 		}
+	}
+	levels, closeLogs, err := logger.Setup(logOpts, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init logging:", err)
+		os.Exit(1)
+	}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+	var code int
+	switch {
+	case len(args) == 0:
+		code = runServer(cfg, levels)
+	case args[0] == "migrate":
+		code = runMigrate(cfg, args[1:])
+	case args[0] == "config":
+		code = runConfig(cfg, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		code = 2
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	// os.Exit skips deferred calls, so the log file is closed here.
+	if err := closeLogs(); err != nil {
+		fmt.Fprintln(os.Stderr, "error closing log file:", err)
+	}
+	os.Exit(code)
 }
 
-func main() {
-	// Инициализация логгера
-	logLevel := os.Getenv("LOG_LEVEL")
-	if logLevel == "" {
-		logLevel = "INFO"
-	}
-	logger.InitLogger(logLevel)
+// runServer serves until SIGINT or SIGTERM and returns the exit code. The
+// deferred cleanup runs before main exits.
+func runServer(cfg config.Config, levels *logger.Levels) int {
+	slog.Info("starting hotel booking service", "log_level", cfg.Log.Level, "storage", cfg.Storage.Driver, "tracing", cfg.Tracing.Exporter)
 
-	slog.Info("starting hotel booking service", "log_level", logLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := db.InitDB(); err != nil {
-		slog.Error("failed to init database", "error", err.Error())
-		return
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, os.Stdout)
+	if err != nil {
+		slog.Error("failed to init tracing", "error", err.Error())
+		return 1
 	}
-
+	// Flush the last spans after the server has stopped.
 	defer func() {
-		if err := db.DB.Close(); err != nil {
-			slog.Error("error closing database", "error", err.Error())
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("error flushing traces", "error", err.Error())
 		}
 	}()
 
-	// Инициализация репозиториев
-	roomRepo := &repository.PgRoomRepository{DB: db.DB}
-	bookingRepo := &repository.PgBookingRepository{DB: db.DB}
-
-	// Инициализация usecase с логгером
-	roomUC := usecase.NewRoomUsecase(roomRepo, slog.Default())
-	bookingUC := usecase.NewBookingUsecase(bookingRepo, slog.Default())
-
-	if err := hn.InitDependencies(roomUC); err != nil {
-		slog.Error("handlers init failed", "error", err.Error())
-		log.Fatalf("handlers init: %v", err)
+	a, err := app.New(ctx, cfg, slog.Default())
+	if err != nil {
+		slog.Error("failed to init application", "error", err.Error())
+		return 1
 	}
 
-	if err := hn.InitBookingDependencies(bookingUC); err != nil {
-		slog.Error("booking handlers init failed", "error", err.Error())
-		log.Fatalf("handlers init: %v", err)
-	}
+	a.LogLevels = levels
 
-	http.HandleFunc("/Create", hn.Create)
-	http.HandleFunc("/RemoveRoom", hn.RemoveRoom)
-	http.HandleFunc("/Patch", hn.Patch)
-	http.HandleFunc("/GetFilteredRooms", hn.GetFilteredRooms)
+	slog.Info("Swagger UI available at /swagger/index.html")
+	slog.Info("Probes available at /livez and /readyz")
 
-	http.HandleFunc("/CreateBooking", hn.CreateBooking)
-	http.HandleFunc("/ReadBookingByID", hn.ReadBookingByID)
-	http.HandleFunc("/PatchBookingByID", hn.PatchBookingByID)
-	http.HandleFunc("/RemoveBooking", hn.RemoveBooking)
-	http.HandleFunc("/GetFilteredBookings", hn.GetFilteredBookings)
-
-	http.Handle("/swagger/", httpSwagger.WrapHandler)
-
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte(`{"status": "ok"}`)); err != nil {
-			log.Printf("Error writing health response: %v", err)
-		}
-	})
-
-	log.Println("server running on http://localhost:8080")
-	log.Println("Swagger UI available at http://localhost:8080/swagger/index.html")
-	log.Println("Health check available at http://localhost:8080/health")
-
-	handler := withCORS(http.DefaultServeMux)
-	if err := http.ListenAndServe(":8080", handler); err != nil {
-		log.Println("the server is not running", err)
+	// Run returns once the server has drained and the workers have stopped,
+	// so the database is closed only after nothing uses it any more.
+	code := 0
+	if err := a.Run(ctx); err != nil {
+		slog.Error("server error", "error", err.Error())
+		code = 1
+	}
+	if err := a.Close(); err != nil {
+		slog.Error("error closing application", "error", err.Error())
 	}
+	return code
 }